@@ -0,0 +1,146 @@
+package envcfg
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+)
+
+// this file holds the parsers that envcfg builds on the fly for slice and map fields, out of
+// whatever parsers the Loader has for their element types.
+
+const (
+	sepTag       = "sep"
+	kvSepTag     = "kvsep"
+	defaultSep   = ','
+	defaultKVSep = '='
+)
+
+// fieldParser returns the parser for the given struct field loaded from numArgs variables.  If the
+// Loader has no parser for the field's type, and it's a slice or map of types that the Loader can
+// parse, then a parser is built that splits the variable's value on the field's separators and
+// parses each element.  The error return is only for invalid struct tags.
+func (e *Loader) fieldParser(field reflect.StructField, fieldPath string, numArgs int) (parser, bool, error) {
+	p, ok := e.findParser(field.Type, numArgs)
+	if ok || numArgs != 1 {
+		return p, ok, nil
+	}
+
+	if field.Type.Kind() == reflect.Slice {
+		elemParser, ok := e.findParser(field.Type.Elem(), 1)
+		if !ok {
+			return parser{}, false, nil
+		}
+		sep, err := separator(field, fieldPath, sepTag, defaultSep)
+		if err != nil {
+			return parser{}, false, err
+		}
+		return sliceParser(field.Type, elemParser, sep), true, nil
+	}
+
+	if field.Type.Kind() == reflect.Map {
+		keyParser, ok := e.findParser(field.Type.Key(), 1)
+		if !ok {
+			return parser{}, false, nil
+		}
+		valParser, ok := e.findParser(field.Type.Elem(), 1)
+		if !ok {
+			return parser{}, false, nil
+		}
+		sep, err := separator(field, fieldPath, sepTag, defaultSep)
+		if err != nil {
+			return parser{}, false, err
+		}
+		kvSep, err := separator(field, fieldPath, kvSepTag, defaultKVSep)
+		if err != nil {
+			return parser{}, false, err
+		}
+		if sep == kvSep {
+			return parser{}, false, &InvalidTagError{
+				Field: fieldPath,
+				Tag:   kvSepTag,
+				Value: string(kvSep),
+				Want:  "different from the " + sepTag + " separator",
+			}
+		}
+		return mapParser(field.Type, keyParser, valParser, sep, kvSep), true, nil
+	}
+	return parser{}, false, nil
+}
+
+// sliceParser returns a parser for a slice of typ that splits its input on sep (allowing
+// backslash-escaped separators, like default tags) and parses each element with elemParser.
+func sliceParser(typ reflect.Type, elemParser parser, sep rune) parser {
+	return parser{
+		f: func(ss ...string) (reflect.Value, error) {
+			out := reflect.MakeSlice(typ, 0, 0)
+			if ss[0] == "" {
+				return out, nil
+			}
+			for i, s := range splitEscaped(ss[0], sep) {
+				v, err := elemParser.f(s)
+				if err != nil {
+					return reflect.Value{}, fmt.Errorf("element %d: %v", i, err)
+				}
+				out = reflect.Append(out, v)
+			}
+			return out, nil
+		},
+		numArgs: 1,
+		name:    fmt.Sprintf("%s for each %q-separated element", elemParser.name, sep),
+	}
+}
+
+// mapParser returns a parser for a map of typ that splits its input into pairs on sep, splits each
+// pair into a key and value on the first kvSep, and parses those with keyParser and valParser.
+// Separators can be escaped with a backslash.
+func mapParser(typ reflect.Type, keyParser, valParser parser, sep, kvSep rune) parser {
+	return parser{
+		f: func(ss ...string) (reflect.Value, error) {
+			out := reflect.MakeMap(typ)
+			if ss[0] == "" {
+				return out, nil
+			}
+			for i, pair := range splitEscaped(ss[0], sep) {
+				parts := splitEscaped(pair, kvSep)
+				if len(parts) < 2 {
+					return reflect.Value{}, fmt.Errorf("pair %d (%q) has no %q separator", i, pair, kvSep)
+				}
+				keyString := parts[0]
+				// anything after the first separator belongs to the value.
+				valString := strings.Join(parts[1:], string(kvSep))
+
+				k, err := keyParser.f(keyString)
+				if err != nil {
+					return reflect.Value{}, fmt.Errorf("key %q: %v", keyString, err)
+				}
+				if out.MapIndex(k).IsValid() {
+					return reflect.Value{}, fmt.Errorf("duplicate key %q", keyString)
+				}
+				v, err := valParser.f(valString)
+				if err != nil {
+					return reflect.Value{}, fmt.Errorf("value for key %q: %v", keyString, err)
+				}
+				out.SetMapIndex(k, v)
+			}
+			return out, nil
+		},
+		numArgs: 1,
+		name:    fmt.Sprintf("%s%c%s for each %q-separated pair", keyParser.name, kvSep, valParser.name, sep),
+	}
+}
+
+// separator reads a single-character separator from the named tag on field, or returns def if the
+// tag isn't set.
+func separator(field reflect.StructField, fieldPath string, tag string, def rune) (rune, error) {
+	s, ok := field.Tag.Lookup(tag)
+	if !ok {
+		return def, nil
+	}
+	if utf8.RuneCountInString(s) != 1 {
+		return 0, &InvalidTagError{Field: fieldPath, Tag: tag, Value: s, Want: "a single character"}
+	}
+	r, _ := utf8.DecodeRuneInString(s)
+	return r, nil
+}
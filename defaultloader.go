@@ -1,6 +1,10 @@
 package envcfg
 
-import "fmt"
+import (
+	"flag"
+	"fmt"
+	"io"
+)
 
 // this file ensures that a default loader is created and available on the package, so users with
 // simple cases can just do envcfg.Load.
@@ -22,11 +26,59 @@ func Load(c interface{}) error {
 	return defaultLoader.Load(c)
 }
 
+// LoadFrom loads config from the provided sources into the provided struct.  Each variable is read
+// from the first source that has it, so sources should be given highest priority first.
+func LoadFrom(c interface{}, sources ...Source) error {
+	return defaultLoader.LoadFrom(c, sources...)
+}
+
 // LoadFromMap loads config from the provided map into the provided struct.
 func LoadFromMap(vals map[string]string, c interface{}) error {
 	return defaultLoader.LoadFromMap(vals, c)
 }
 
+// LoadWithReport loads config from the environment into the provided struct, and also returns a
+// Report of where each field's values came from.
+func LoadWithReport(c interface{}) (*Report, error) {
+	return defaultLoader.LoadWithReport(c)
+}
+
+// LoadFromWithReport loads config from the provided sources into the provided struct, and also
+// returns a Report of where each field's values came from.
+func LoadFromWithReport(c interface{}, sources ...Source) (*Report, error) {
+	return defaultLoader.LoadFromWithReport(c, sources...)
+}
+
+// LoadFromDotenv loads config from the named .env files into the provided struct.
+func LoadFromDotenv(c interface{}, paths ...string) error {
+	return defaultLoader.LoadFromDotenv(c, paths...)
+}
+
+// BindFlags defines a flag on fs for each variable that the fields of c are loaded from, and makes
+// the default loader give the flags that are set priority over every other source.  That lasts for
+// the life of the process, and applies to every later load with the package-level functions, for
+// any struct.  Use a Loader from New to keep flags to one part of a program.
+func BindFlags(fs *flag.FlagSet, c interface{}) error {
+	return defaultLoader.BindFlags(fs, c)
+}
+
+// WriteUsage writes a table to w describing each variable that c's fields are loaded from by the
+// default loader.
+func WriteUsage(w io.Writer, c interface{}) error {
+	return defaultLoader.WriteUsage(w, c)
+}
+
+// Usage returns the table that WriteUsage would write for c.
+func Usage(c interface{}) (string, error) {
+	return defaultLoader.Usage(c)
+}
+
+// JSONSchema returns a JSON Schema document describing the variables that c's fields are loaded
+// from by the default loader.
+func JSONSchema(c interface{}) ([]byte, error) {
+	return defaultLoader.JSONSchema(c)
+}
+
 // RegisterParser takes a func (string) (<anytype>, error) and registers it on the default loader
 // as the parser for <anytype>.
 func RegisterParser(f interface{}) error {
@@ -37,3 +89,28 @@ func RegisterParser(f interface{}) error {
 func MustRegisterParser(f interface{}) {
 	defaultLoader.MustRegisterParser(f)
 }
+
+// Dump writes a table to w listing each variable that c's fields were loaded from by the latest
+// load into c with the default loader, its value, and where the value came from, with the values
+// of fields tagged secret:"true" redacted.
+func Dump(c interface{}, w io.Writer) error {
+	return defaultLoader.Dump(c, w)
+}
+
+// Marshal returns the variables that would load the config struct that c points to, using the
+// default loader's formatters.
+func Marshal(c interface{}) (map[string]string, error) {
+	return defaultLoader.Marshal(c)
+}
+
+// RegisterFormatter takes a func (<anytype>) (string, error) and registers it on the default loader
+// as the formatter for <anytype>.
+func RegisterFormatter(f interface{}) error {
+	return defaultLoader.RegisterFormatter(f)
+}
+
+// MustRegisterFormatter attempts to register the provided formatter func and panics if it gets an
+// error.
+func MustRegisterFormatter(f interface{}) {
+	defaultLoader.MustRegisterFormatter(f)
+}
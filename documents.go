@@ -0,0 +1,220 @@
+package envcfg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// this file holds Sources for config files like JSON, YAML, and TOML.  Their nested documents are
+// flattened into variable names, so that the same env tags (and the same parsers) serve both
+// environment variables and checked-in config files:
+//
+//	{"db": {"host": "localhost", "port": 5432}}   DB_HOST=localhost, DB_PORT=5432
+//	{"hosts": ["a", "b"]}                         HOSTS=a,b
+//	{"upstream": [{"host": "a"}, {"host": "b"}]}  UPSTREAM_0_HOST=a, UPSTREAM_1_HOST=b
+//
+// Lists of scalars are joined with commas (escaping any commas in the elements) for slice fields,
+// and lists of objects are numbered for slices of structs.  Nulls are left out, so fields fall back
+// to their defaults.
+
+// A FlattenOption changes how a document is flattened into variable names.
+type FlattenOption func(*flattener)
+
+// KeySeparator sets the string used to join the keys of nested objects.  The default is "_".
+func KeySeparator(sep string) FlattenOption {
+	return func(f *flattener) {
+		f.sep = sep
+	}
+}
+
+// KeyCase sets the func applied to each key in the document before they're joined into a variable
+// name.  The default is strings.ToUpper.
+func KeyCase(keyCase func(string) string) FlattenOption {
+	return func(f *flattener) {
+		f.keyCase = keyCase
+	}
+}
+
+// DocumentSource returns a Source holding the values in doc, which should be a document decoded
+// from JSON, YAML, TOML or the like into maps, slices, and scalars.
+func DocumentSource(doc interface{}, opts ...FlattenOption) (Source, error) {
+	f := &flattener{sep: "_", keyCase: strings.ToUpper, vals: map[string]string{}}
+	for _, opt := range opts {
+		opt(f)
+	}
+	if err := f.flatten("", reflect.ValueOf(doc)); err != nil {
+		return nil, err
+	}
+	return MapSource(f.vals), nil
+}
+
+// FileSource returns a Source holding the values in the named file, decoded with unmarshal and
+// flattened as by DocumentSource.  Any func with the signature of json.Unmarshal will do, so
+// YAML and TOML files can be loaded with FileSource(path, yaml.Unmarshal) or
+// FileSource(path, toml.Unmarshal) without envcfg depending on those packages.
+func FileSource(path string, unmarshal func([]byte, interface{}) error, opts ...FlattenOption) (Source, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("envcfg: %v", err)
+	}
+	var doc interface{}
+	if err := unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("envcfg: %s: %v", path, err)
+	}
+	return fileDocumentSource(path, doc, opts)
+}
+
+// JSONFileSource returns a Source holding the values in the named JSON file, flattened as by
+// DocumentSource.  Numbers are kept exactly as they're written in the file.
+func JSONFileSource(path string, opts ...FlattenOption) (Source, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("envcfg: %v", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("envcfg: %s: %v", path, err)
+	}
+	return fileDocumentSource(path, doc, opts)
+}
+
+func fileDocumentSource(path string, doc interface{}, opts []FlattenOption) (Source, error) {
+	source, err := DocumentSource(doc, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("%v in %s", err, path)
+	}
+	return source, nil
+}
+
+// flattener turns a document into variables.
+type flattener struct {
+	sep     string
+	keyCase func(string) string
+	vals    map[string]string
+}
+
+func (f *flattener) join(prefix, key string) string {
+	key = f.keyCase(key)
+	if prefix == "" {
+		return key
+	}
+	return prefix + f.sep + key
+}
+
+func (f *flattener) set(key, val string) error {
+	if key == "" {
+		return fmt.Errorf("envcfg: cannot use a %q value without a key", val)
+	}
+	if _, ok := f.vals[key]; ok {
+		return fmt.Errorf("envcfg: more than one value for %s", key)
+	}
+	f.vals[key] = val
+	return nil
+}
+
+// flatten adds the variables for v, whose keys all start with prefix.
+func (f *flattener) flatten(prefix string, v reflect.Value) error {
+	for v.IsValid() && (v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr) {
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		// a null.  Leave the variable unset.
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Map:
+		// sort the keys so that errors are reported in a predictable order.
+		keys := v.MapKeys()
+		names := make([]string, len(keys))
+		for i, k := range keys {
+			names[i] = fmt.Sprint(k.Interface())
+		}
+		order := make([]int, len(keys))
+		for i := range order {
+			order[i] = i
+		}
+		sort.Slice(order, func(i, j int) bool { return names[order[i]] < names[order[j]] })
+		for _, i := range order {
+			if err := f.flatten(f.join(prefix, names[i]), v.MapIndex(keys[i])); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			break
+		}
+		if !isScalarList(v) {
+			for i := 0; i < v.Len(); i++ {
+				if err := f.flatten(f.join(prefix, strconv.Itoa(i)), v.Index(i)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		elems := make([]string, v.Len())
+		for i := range elems {
+			elems[i] = escapeElem(scalarString(v.Index(i)), defaultSep)
+		}
+		return f.set(prefix, strings.Join(elems, string(defaultSep)))
+	}
+	return f.set(prefix, scalarString(v))
+}
+
+// isScalarList reports whether none of the elements of v are objects or lists.
+func isScalarList(v reflect.Value) bool {
+	for i := 0; i < v.Len(); i++ {
+		elem := v.Index(i)
+		for elem.Kind() == reflect.Interface || elem.Kind() == reflect.Ptr {
+			elem = elem.Elem()
+		}
+		switch elem.Kind() {
+		case reflect.Map, reflect.Slice, reflect.Array:
+			return false
+		}
+	}
+	return true
+}
+
+// scalarString formats a scalar value the way the default parsers expect it.
+func scalarString(v reflect.Value) string {
+	for v.IsValid() && (v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr) {
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return ""
+	}
+	switch x := v.Interface().(type) {
+	case time.Time:
+		return x.Format(time.RFC3339Nano)
+	case []byte:
+		return string(x)
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(x), 'f', -1, 32)
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// escapeElem escapes any separators in s, so it can be used as one element of a list split on sep.
+func escapeElem(s string, sep rune) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r == sep {
+			b.WriteRune(backSlash)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,130 @@
+package envcfg
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONFileSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	assert.Nil(t, os.WriteFile(path, []byte(`{
+		"name": "myapp",
+		"debug": true,
+		"db": {"host": "localhost", "port": 5432, "password": null},
+		"big": 12345678901234567890,
+		"ratio": 0.25,
+		"hosts": ["a.example.com", "b,c.example.com"],
+		"upstream": [{"host": "u0"}, {"host": "u1", "port": 8080}],
+		"tenant": {"acme": {"name": "Acme"}}
+	}`), 0600))
+
+	source, err := JSONFileSource(path)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"BIG",
+		"DB_HOST",
+		"DB_PORT",
+		"DEBUG",
+		"HOSTS",
+		"NAME",
+		"RATIO",
+		"TENANT_ACME_NAME",
+		"UPSTREAM_0_HOST",
+		"UPSTREAM_1_HOST",
+		"UPSTREAM_1_PORT",
+	}, source.Keys())
+	big, _ := source.Lookup("BIG")
+	assert.Equal(t, "12345678901234567890", big)
+
+	type DBConfig struct {
+		Host     string `env:"HOST"`
+		Port     int    `env:"PORT"`
+		Password string `env:"PASSWORD" default:"secret"`
+	}
+	type UpstreamConfig struct {
+		Host string `env:"HOST"`
+		Port int    `env:"PORT" default:"80"`
+	}
+	type TenantConfig struct {
+		Name string `env:"NAME"`
+	}
+	type myConfig struct {
+		Name      string                  `env:"NAME"`
+		Debug     bool                    `env:"DEBUG"`
+		Ratio     float64                 `env:"RATIO"`
+		Hosts     []string                `env:"HOSTS"`
+		DB        DBConfig                `envPrefix:"DB_"`
+		Upstreams []UpstreamConfig        `envPrefix:"UPSTREAM_"`
+		Tenants   map[string]TenantConfig `envPrefix:"TENANT_"`
+	}
+	var conf myConfig
+	err = LoadFrom(&conf, MapSource{"NAME": "from-env"}, source)
+	assert.Nil(t, err)
+	assert.Equal(t, myConfig{
+		Name:      "from-env",
+		Debug:     true,
+		Ratio:     0.25,
+		Hosts:     []string{"a.example.com", "b,c.example.com"},
+		DB:        DBConfig{Host: "localhost", Port: 5432, Password: "secret"},
+		Upstreams: []UpstreamConfig{{Host: "u0", Port: 80}, {Host: "u1", Port: 8080}},
+		Tenants:   map[string]TenantConfig{"ACME": {Name: "Acme"}},
+	}, conf)
+}
+
+func TestFileSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.Nil(t, os.WriteFile(path, []byte("ignored"), 0600))
+
+	// decoders like gopkg.in/yaml.v2 produce maps with interface{} keys, and TOML has dates.
+	when := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	unmarshal := func(b []byte, v interface{}) error {
+		*(v.(*interface{})) = map[interface{}]interface{}{
+			"db":      map[interface{}]interface{}{"host": "localhost", "port": 5432},
+			"started": when,
+			"ports":   []interface{}{80, 443},
+		}
+		return nil
+	}
+	source, err := FileSource(path, unmarshal, KeySeparator("."), KeyCase(strings.ToLower))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"db.host", "db.port", "ports", "started"}, source.Keys())
+
+	type myConfig struct {
+		Host    string    `env:"db.host"`
+		Port    int       `env:"db.port"`
+		Ports   []int     `env:"ports"`
+		Started time.Time `env:"started"`
+	}
+	var conf myConfig
+	err = LoadFrom(&conf, source)
+	assert.Nil(t, err)
+	assert.Equal(t, myConfig{Host: "localhost", Port: 5432, Ports: []int{80, 443}, Started: when}, conf)
+}
+
+func TestDocumentSourceErrors(t *testing.T) {
+	_, err := DocumentSource(map[string]interface{}{"db_host": "a", "db": map[string]interface{}{"host": "b"}})
+	assert.Equal(t, "envcfg: more than one value for DB_HOST", err.Error())
+
+	_, err = DocumentSource("hello")
+	assert.Equal(t, `envcfg: cannot use a "hello" value without a key`, err.Error())
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bad.json")
+	assert.Nil(t, os.WriteFile(path, []byte(`{"a": `), 0600))
+	_, err = JSONFileSource(path)
+	assert.Equal(t, "envcfg: "+path+": unexpected EOF", err.Error())
+
+	path = filepath.Join(dir, "dup.json")
+	assert.Nil(t, os.WriteFile(path, []byte(`{"A": 1, "a": 2}`), 0600))
+	_, err = JSONFileSource(path)
+	assert.Equal(t, "envcfg: more than one value for A in "+path, err.Error())
+
+	path = filepath.Join(dir, "missing.json")
+	_, err = JSONFileSource(path)
+	assert.Equal(t, "envcfg: open "+path+": no such file or directory", err.Error())
+}
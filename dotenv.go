@@ -0,0 +1,326 @@
+package envcfg
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// this file holds a parser for .env files, so that config can be loaded from them with the same
+// struct tags and parsers as environment variables.
+//
+// The format is the one most dotenv libraries agree on:
+//
+//	# comments start with a hash
+//	export FOO=bar                  # "export " is optional, and so are trailing comments
+//	UNQUOTED=some value             # surrounding whitespace is trimmed
+//	SINGLE='no $interpolation\n'    # taken literally
+//	DOUBLE="line one\nline two"     # escapes: \n \r \t \" \\ \$
+//	MULTILINE="first line
+//	second line"                    # quoted values can span lines
+//	URL=http://${HOST}:$PORT/       # ${VAR}, $VAR, and ${VAR:-default} are expanded
+//
+// Interpolation uses variables defined earlier in the same or an earlier file, then the process
+// environment.  Undefined variables expand to an empty string.
+
+// DotenvError is returned for a syntax error in a .env file.
+type DotenvError struct {
+	File string
+	Line int
+	Msg  string
+}
+
+func (e *DotenvError) Error() string {
+	return fmt.Sprintf("envcfg: %s:%d: %s", e.File, e.Line, e.Msg)
+}
+
+// ReadDotenv parses the named .env files and returns all of their variables.  If a variable is set
+// in more than one file, the last one wins.
+func ReadDotenv(paths ...string) (map[string]string, error) {
+	vals := map[string]string{}
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("envcfg: %v", err)
+		}
+		p := &dotenvParser{file: path, src: string(b), line: 1, vals: vals}
+		if err := p.parse(); err != nil {
+			return nil, err
+		}
+	}
+	return vals, nil
+}
+
+// DotenvSource returns a Source holding the variables from the named .env files, as read by
+// ReadDotenv.
+func DotenvSource(paths ...string) (Source, error) {
+	vals, err := ReadDotenv(paths...)
+	if err != nil {
+		return nil, err
+	}
+	return MapSource(vals), nil
+}
+
+// LoadFromDotenv loads config from the named .env files into the provided struct.
+func (e *Loader) LoadFromDotenv(c interface{}, paths ...string) error {
+	source, err := DotenvSource(paths...)
+	if err != nil {
+		return err
+	}
+	return e.LoadFrom(c, source)
+}
+
+// dotenvParser reads one file's worth of variables into vals.
+type dotenvParser struct {
+	file string
+	src  string
+	pos  int
+	line int
+	vals map[string]string
+}
+
+func (p *dotenvParser) errorf(line int, format string, args ...interface{}) error {
+	return &DotenvError{File: p.file, Line: line, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *dotenvParser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *dotenvParser) peek() byte {
+	return p.src[p.pos]
+}
+
+// next consumes one byte, counting lines as it goes.
+func (p *dotenvParser) next() byte {
+	c := p.src[p.pos]
+	p.pos++
+	if c == '\n' {
+		p.line++
+	}
+	return c
+}
+
+func (p *dotenvParser) skipBlanks() {
+	for !p.eof() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.next()
+	}
+}
+
+func (p *dotenvParser) skipLine() {
+	for !p.eof() && p.next() != '\n' {
+	}
+}
+
+func (p *dotenvParser) parse() error {
+	for {
+		// skip blank lines and comments
+		for !p.eof() && strings.ContainsRune(" \t\r\n", rune(p.peek())) {
+			p.next()
+		}
+		if p.eof() {
+			return nil
+		}
+		if p.peek() == '#' {
+			p.skipLine()
+			continue
+		}
+
+		line := p.line
+		key := p.readName()
+		if key == "export" && !p.eof() && (p.peek() == ' ' || p.peek() == '\t') {
+			p.skipBlanks()
+			key = p.readName()
+		}
+		if key == "" {
+			return p.errorf(line, "expected a variable name, found %q", p.restOfLine())
+		}
+		p.skipBlanks()
+		if p.eof() || p.peek() != '=' {
+			return p.errorf(line, "expected = after %s", key)
+		}
+		p.next()
+		p.skipBlanks()
+
+		var val string
+		var err error
+		switch {
+		case p.eof():
+		case p.peek() == '\'':
+			val, err = p.readSingleQuoted(line)
+		case p.peek() == '"':
+			val, err = p.readDoubleQuoted(line)
+		default:
+			val, err = p.readUnquoted()
+		}
+		if err != nil {
+			return err
+		}
+
+		// after the value there can only be a comment.
+		p.skipBlanks()
+		if !p.eof() && p.peek() == '#' {
+			p.skipLine()
+		} else if !p.eof() && p.peek() != '\n' && p.peek() != '\r' {
+			return p.errorf(p.line, "unexpected %q after the value of %s", p.restOfLine(), key)
+		}
+		p.vals[key] = val
+	}
+}
+
+// restOfLine returns what's left of the current line, for error messages.
+func (p *dotenvParser) restOfLine() string {
+	rest := p.src[p.pos:]
+	if i := strings.IndexByte(rest, '\n'); i >= 0 {
+		rest = rest[:i]
+	}
+	return strings.TrimRight(rest, "\r")
+}
+
+func isNameChar(c byte, first bool) bool {
+	return c == '_' || ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || (!first && '0' <= c && c <= '9')
+}
+
+// readName reads a variable name, returning "" if there isn't one at the current position.
+func (p *dotenvParser) readName() string {
+	start := p.pos
+	for !p.eof() && isNameChar(p.peek(), p.pos == start) {
+		p.next()
+	}
+	return p.src[start:p.pos]
+}
+
+// readSingleQuoted reads a value in single quotes, which is taken literally.
+func (p *dotenvParser) readSingleQuoted(line int) (string, error) {
+	p.next()
+	end := strings.IndexByte(p.src[p.pos:], '\'')
+	if end < 0 {
+		return "", p.errorf(line, "unterminated single-quoted value")
+	}
+	val := p.src[p.pos : p.pos+end]
+	for i := 0; i <= end; i++ {
+		p.next()
+	}
+	return val, nil
+}
+
+// readDoubleQuoted reads a value in double quotes, handling escapes and interpolation.
+func (p *dotenvParser) readDoubleQuoted(line int) (string, error) {
+	p.next()
+	var b strings.Builder
+	for {
+		if p.eof() {
+			return "", p.errorf(line, "unterminated double-quoted value")
+		}
+		c := p.peek()
+		switch c {
+		case '"':
+			p.next()
+			return b.String(), nil
+		case '\\':
+			p.next()
+			if p.eof() {
+				return "", p.errorf(line, "unterminated double-quoted value")
+			}
+			switch e := p.next(); e {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case '"', '\\', '$':
+				b.WriteByte(e)
+			default:
+				// not an escape we know. Keep it as is.
+				b.WriteByte('\\')
+				b.WriteByte(e)
+			}
+		case '$':
+			s, err := p.readReference()
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(s)
+		default:
+			b.WriteByte(p.next())
+		}
+	}
+}
+
+// readUnquoted reads a value up to the end of the line or a comment, handling interpolation.  A #
+// only starts a comment at the start of the value or after whitespace, so URL fragments are safe.
+func (p *dotenvParser) readUnquoted() (string, error) {
+	var b strings.Builder
+	start := p.pos
+	for !p.eof() && p.peek() != '\n' {
+		c := p.peek()
+		if c == '#' && (p.pos == start || p.src[p.pos-1] == ' ' || p.src[p.pos-1] == '\t') {
+			break
+		}
+		if c == '$' {
+			s, err := p.readReference()
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(s)
+			continue
+		}
+		b.WriteByte(p.next())
+	}
+	return strings.TrimRight(b.String(), " \t\r"), nil
+}
+
+// readReference reads a $VAR, ${VAR}, or ${VAR:-default} reference at the current position and
+// returns its value.  A $ that isn't followed by a name is just a $.
+func (p *dotenvParser) readReference() (string, error) {
+	line := p.line
+	p.next()
+	if p.eof() {
+		return "$", nil
+	}
+	if p.peek() != '{' {
+		name := p.readName()
+		if name == "" {
+			return "$", nil
+		}
+		return p.lookup(name), nil
+	}
+
+	p.next()
+	end := strings.IndexByte(p.src[p.pos:], '}')
+	if end < 0 || strings.IndexByte(p.src[p.pos:p.pos+end], '\n') >= 0 {
+		return "", p.errorf(line, "unterminated ${ reference")
+	}
+	ref := p.src[p.pos : p.pos+end]
+	for i := 0; i <= end; i++ {
+		p.next()
+	}
+
+	name, def, hasDefault := ref, "", false
+	if i := strings.Index(ref, ":-"); i >= 0 {
+		name, def, hasDefault = ref[:i], ref[i+2:], true
+	}
+	for i := 0; i < len(name); i++ {
+		if !isNameChar(name[i], i == 0) {
+			return "", p.errorf(line, "invalid variable name %q in ${%s}", name, ref)
+		}
+	}
+	if name == "" {
+		return "", p.errorf(line, "missing variable name in ${%s}", ref)
+	}
+	val := p.lookup(name)
+	if val == "" && hasDefault {
+		return def, nil
+	}
+	return val, nil
+}
+
+// lookup finds the value of a variable for interpolation: first in the variables read so far, and
+// then in the process environment.
+func (p *dotenvParser) lookup(name string) string {
+	if val, ok := p.vals[name]; ok {
+		return val
+	}
+	return os.Getenv(name)
+}
@@ -0,0 +1,161 @@
+package envcfg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeDotenv(t *testing.T, dir, name, contents string) string {
+	path := filepath.Join(dir, name)
+	err := os.WriteFile(path, []byte(contents), 0600)
+	assert.Nil(t, err)
+	return path
+}
+
+func TestReadDotenv(t *testing.T) {
+	os.Setenv("DOTENV_FROM_ENV", "from env")
+	dir := t.TempDir()
+	path := writeDotenv(t, dir, ".env", `# a comment
+export EXPORTED=yes
+PLAIN = some value   # trailing comment
+HASH=http://example.com/#anchor
+EMPTY=
+EMPTY_COMMENT= # nothing here
+SINGLE='single $PLAIN \n # not a comment'
+DOUBLE="tab\there \"quoted\" \$PLAIN \\ \q"
+MULTILINE="first line
+second line"
+MULTI_SINGLE='one
+two'
+INTERPOLATED=${PLAIN}-$EXPORTED-${DOTENV_FROM_ENV}
+DEFAULTED=${DOTENV_UNDEFINED:-fallback} ${EMPTY:-also fallback} ${PLAIN:-unused}
+UNDEFINED=[$DOTENV_UNDEFINED]
+DOLLARS=$5 costs $ 5
+CRLF=windows`+"\r"+`
+`)
+
+	vals, err := ReadDotenv(path)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{
+		"EXPORTED":      "yes",
+		"PLAIN":         "some value",
+		"HASH":          "http://example.com/#anchor",
+		"EMPTY":         "",
+		"EMPTY_COMMENT": "",
+		"SINGLE":        `single $PLAIN \n # not a comment`,
+		"DOUBLE":        "tab\there \"quoted\" $PLAIN \\ \\q",
+		"MULTILINE":     "first line\nsecond line",
+		"MULTI_SINGLE":  "one\ntwo",
+		"INTERPOLATED":  "some value-yes-from env",
+		"DEFAULTED":     "fallback also fallback some value",
+		"UNDEFINED":     "[]",
+		"DOLLARS":       "$5 costs $ 5",
+		"CRLF":          "windows",
+	}, vals)
+}
+
+func TestReadDotenvLayers(t *testing.T) {
+	dir := t.TempDir()
+	first := writeDotenv(t, dir, ".env", "A=1\nB=2\n")
+	second := writeDotenv(t, dir, ".env.local", "B=3\nC=${A}${B}\n")
+
+	vals, err := ReadDotenv(first, second)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"A": "1", "B": "3", "C": "13"}, vals)
+}
+
+func TestReadDotenvErrors(t *testing.T) {
+	tt := []struct {
+		desc     string
+		contents string
+		err      string
+	}{
+		{
+			desc:     "no equals",
+			contents: "A=1\nJUST_A_NAME\n",
+			err:      "envcfg: %s:2: expected = after JUST_A_NAME",
+		},
+		{
+			desc:     "bad name",
+			contents: "\n\n1A=2\n",
+			err:      `envcfg: %s:3: expected a variable name, found "1A=2"`,
+		},
+		{
+			desc:     "unterminated double quote",
+			contents: "A=1\nB=\"one\ntwo\n",
+			err:      "envcfg: %s:2: unterminated double-quoted value",
+		},
+		{
+			desc:     "unterminated single quote",
+			contents: "A='one",
+			err:      "envcfg: %s:1: unterminated single-quoted value",
+		},
+		{
+			desc:     "junk after quotes",
+			contents: "A=\"one\"\nB='two' three\n",
+			err:      `envcfg: %s:2: unexpected "three" after the value of B`,
+		},
+		{
+			desc:     "unterminated reference",
+			contents: "A=${B\nC=}\n",
+			err:      "envcfg: %s:1: unterminated ${ reference",
+		},
+		{
+			desc:     "bad reference",
+			contents: "A=${B C}\n",
+			err:      `envcfg: %s:1: invalid variable name "B C" in ${B C}`,
+		},
+	}
+
+	dir := t.TempDir()
+	for _, tc := range tt {
+		t.Run(tc.desc, func(t *testing.T) {
+			path := writeDotenv(t, dir, ".env", tc.contents)
+			_, err := ReadDotenv(path)
+			assert.Equal(t, fmt.Sprintf(tc.err, path), err.Error())
+		})
+	}
+
+	_, err := ReadDotenv(filepath.Join(dir, "missing.env"))
+	assert.Equal(t, "envcfg: open "+filepath.Join(dir, "missing.env")+": no such file or directory", err.Error())
+}
+
+func TestLoadFromDotenv(t *testing.T) {
+	type myConfig struct {
+		Name    string        `env:"NAME"`
+		Timeout time.Duration `env:"TIMEOUT" default:"1s"`
+	}
+
+	path := writeDotenv(t, t.TempDir(), ".env", "NAME=\"my app\"\nTIMEOUT=5m\n")
+	var conf myConfig
+	err := LoadFromDotenv(&conf, path)
+	assert.Nil(t, err)
+	assert.Equal(t, myConfig{Name: "my app", Timeout: 5 * time.Minute}, conf)
+}
+
+func TestLoadFromLayers(t *testing.T) {
+	type myConfig struct {
+		A string `env:"LAYER_A" default:"default a"`
+		B string `env:"LAYER_B" default:"default b"`
+		C string `env:"LAYER_C" default:"default c"`
+		D string `env:"LAYER_D" default:"default d"`
+	}
+
+	os.Setenv("LAYER_A", "env a")
+	os.Setenv("LAYER_B", "env b")
+	defer os.Unsetenv("LAYER_A")
+	defer os.Unsetenv("LAYER_B")
+	dotenv, err := DotenvSource(writeDotenv(t, t.TempDir(), ".env", "LAYER_B=dotenv b\nLAYER_C=dotenv c\n"))
+	assert.Nil(t, err)
+	overrides := MapSource{"LAYER_A": "override a"}
+
+	var conf myConfig
+	err = LoadFrom(&conf, overrides, EnvSource(), dotenv)
+	assert.Nil(t, err)
+	assert.Equal(t, myConfig{A: "override a", B: "env b", C: "dotenv c", D: "default d"}, conf)
+}
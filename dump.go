@@ -0,0 +1,67 @@
+package envcfg
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+)
+
+// this file holds the dumping of a config's effective values, for logging at startup without
+// leaking secrets.
+
+// redacted is shown in place of the values of fields tagged secret:"true".
+const redacted = "[REDACTED]"
+
+// Dump writes a table to w listing each variable that c's fields were loaded from by the latest
+// load into c with this Loader, like Load or LoadFrom, with its value and where the value came
+// from, as DumpReport does.  The values of fields tagged secret:"true" are redacted, unless they're
+// empty.
+//
+// Dump doesn't read the sources again or look at the values in c, so it shows exactly what was
+// loaded.  It returns an error if c hasn't been loaded, or if a different struct of the same type
+// was loaded since.
+func (e *Loader) Dump(c interface{}, w io.Writer) error {
+	structType, err := configType(c)
+	if err != nil {
+		return err
+	}
+	e.reportMu.Lock()
+	loaded, ok := e.reports[structType]
+	e.reportMu.Unlock()
+	if !ok {
+		return fmt.Errorf("envcfg: cannot dump %v: it hasn't been loaded by this Loader", structType)
+	}
+	if loaded.c != c {
+		return fmt.Errorf("envcfg: cannot dump %v: another one has been loaded since", structType)
+	}
+	return DumpReport(loaded.report, w)
+}
+
+// DumpReport writes a table to w listing each variable in r, from a load with LoadWithReport or
+// LoadFromWithReport, with its value and where the value came from:  env (the environment, from
+// EnvSource), source N (the source at index N in the list given to LoadFrom), flag (a flag bound
+// with BindFlags), file (a file named by a variable with the FileSuffix), default (the field's
+// default tag), or unset.  The values of fields tagged secret:"true" are redacted, unless they're
+// empty.
+func DumpReport(r *Report, w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "VARIABLE\tVALUE\tSOURCE")
+	for _, field := range r.Fields {
+		for _, v := range field.Vars {
+			val := strconv.Quote(v.Value)
+			switch {
+			case v.Origin == OriginUnset:
+				val = ""
+			case field.Secret && v.Value != "":
+				val = redacted
+			}
+			origin := v.Origin.String()
+			if v.Origin == OriginSource {
+				origin = fmt.Sprintf("source %d", v.Source)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", v.Key, val, origin)
+		}
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,100 @@
+package envcfg
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDumpReport(t *testing.T) {
+	type UpstreamConfig struct {
+		Host string `env:"HOST"`
+	}
+	type myConfig struct {
+		Name      string           `env:"NAME"`
+		Password  string           `env:"PASSWORD" secret:"true"`
+		Token     string           `env:"TOKEN" secret:"true" file:"true"`
+		Empty     string           `env:"EMPTY" secret:"true" default:""`
+		Timeout   time.Duration    `env:"TIMEOUT" default:"5s"`
+		Proxy     string           `env:"PROXY" optional:"true"`
+		Debug     bool             `env:"DEBUG" default:"false"`
+		Upstreams []UpstreamConfig `envPrefix:"UPSTREAM_"`
+	}
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	assert.Nil(t, os.WriteFile(tokenFile, []byte("abc123\n"), 0600))
+
+	ec, err := New()
+	assert.Nil(t, err)
+	var conf myConfig
+	fs := flag.NewFlagSet("myapp", flag.ContinueOnError)
+	assert.Nil(t, ec.BindFlags(fs, &conf))
+	assert.Nil(t, fs.Parse([]string{"-debug"}))
+	report, err := ec.LoadFromWithReport(&conf,
+		MapSource{"NAME": "my app", "PASSWORD": "hunter2", "UPSTREAM_0_HOST": "a.example.com"},
+		MapSource{"TOKEN_FILE": tokenFile, "UPSTREAM_1_HOST": "b.example.com"},
+	)
+	assert.Nil(t, err)
+
+	var b bytes.Buffer
+	assert.Nil(t, DumpReport(report, &b))
+	assert.Equal(t, strings.Join([]string{
+		"VARIABLE         VALUE            SOURCE",
+		`NAME             "my app"         source 0`,
+		"PASSWORD         [REDACTED]       source 0",
+		"TOKEN            [REDACTED]       file",
+		`EMPTY            ""               default`,
+		`TIMEOUT          "5s"             default`,
+		"PROXY                             unset",
+		`DEBUG            "true"           flag`,
+		`UPSTREAM_0_HOST  "a.example.com"  source 0`,
+		`UPSTREAM_1_HOST  "b.example.com"  source 1`,
+		"",
+	}, "\n"), b.String())
+}
+
+func TestDump(t *testing.T) {
+	type myConfig struct {
+		Host     string        `env:"DUMP_HOST"`
+		Password string        `env:"DUMP_PASSWORD" secret:"true"`
+		Timeout  time.Duration `env:"DUMP_TIMEOUT" default:"5s"`
+	}
+	os.Setenv("DUMP_HOST", "db.example.com")
+	os.Setenv("DUMP_PASSWORD", "hunter2")
+	defer os.Unsetenv("DUMP_HOST")
+	defer os.Unsetenv("DUMP_PASSWORD")
+
+	ec, err := New()
+	assert.Nil(t, err)
+	var conf myConfig
+	var b bytes.Buffer
+	err = ec.Dump(&conf, &b)
+	assert.Equal(t, "envcfg: cannot dump envcfg.myConfig: it hasn't been loaded by this Loader", err.Error())
+
+	assert.Nil(t, ec.Load(&conf))
+	assert.Nil(t, ec.Dump(&conf, &b))
+	assert.Equal(t, strings.Join([]string{
+		"VARIABLE       VALUE             SOURCE",
+		`DUMP_HOST      "db.example.com"  env`,
+		"DUMP_PASSWORD  [REDACTED]        env",
+		`DUMP_TIMEOUT   "5s"              default`,
+		"",
+	}, "\n"), b.String())
+
+	// the values shown are the ones that were loaded, even if the environment changes
+	os.Setenv("DUMP_HOST", "other.example.com")
+	b.Reset()
+	assert.Nil(t, ec.Dump(&conf, &b))
+	assert.Contains(t, b.String(), `"db.example.com"`)
+
+	var other myConfig
+	assert.Nil(t, ec.LoadFromMap(map[string]string{"DUMP_HOST": "a", "DUMP_PASSWORD": "b"}, &other))
+	err = ec.Dump(&conf, &b)
+	assert.Equal(t, "envcfg: cannot dump envcfg.myConfig: another one has been loaded since", err.Error())
+}
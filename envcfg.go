@@ -5,48 +5,73 @@ package envcfg
 
 import (
 	"fmt"
-	"os"
 	"reflect"
 	"runtime"
 	"strings"
-
-	multierror "github.com/hashicorp/go-multierror"
+	"sync"
 )
 
 const (
 	cfgTag     = "env"
 	defaultTag = "default"
+	prefixTag  = "envPrefix"
 	tagSep     = ","
 	backSlash  = '\\'
 )
 
 var stringType = reflect.TypeOf("")
 
-// New returns a Loader with the default parsers enabled.
-func New() (*Loader, error) {
-	ec := Empty()
+// An Option configures a Loader.
+type Option func(*Loader)
+
+// New returns a Loader with the default parsers and formatters enabled.
+func New(opts ...Option) (*Loader, error) {
+	ec := Empty(opts...)
 	for _, f := range DefaultParsers {
 		err := ec.RegisterParser(f)
 		if err != nil {
 			return nil, err
 		}
 	}
+	for _, f := range DefaultFormatters {
+		err := ec.RegisterFormatter(f)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return ec, nil
 }
 
-// Empty returns a Loader without any parsers enabled.
-func Empty() *Loader {
+// Empty returns a Loader without any parsers or formatters enabled.
+func Empty(opts ...Option) *Loader {
 	ec := &Loader{}
 	ec.parsers = map[parserKey]parser{}
+	ec.formatters = map[parserKey]formatter{}
+	ec.reports = map[reflect.Type]loadedReport{}
+	ec.strategies = DefaultStrategies
+	for _, opt := range opts {
+		opt(ec)
+	}
 	return ec
 }
 
+// StrictPrefix makes the Loader return an error for every variable starting with prefix that isn't
+// used by any field in the config struct, to catch stale or misspelled settings.  It can be given
+// more than once to check several prefixes.
+func StrictPrefix(prefix string) Option {
+	return func(e *Loader) {
+		e.strictPrefixes = append(e.strictPrefixes, prefix)
+	}
+}
+
 // Our internal parser func takes any number of strings and returns a reflect.Value and an error.
 // Funcs of this type wrap the default parsers and user-provided parsers that return arbitrary
 // types.
 type parser struct {
 	f       func(...string) (reflect.Value, error)
 	numArgs int
+	// describes where the parser came from, like "envcfg.ParseInt", for usage text.
+	name string
 }
 
 type parserKey struct {
@@ -60,6 +85,34 @@ type Loader struct {
 	// a map from reflect types to functions that can take a string and return a
 	// reflect value of that type.
 	parsers map[parserKey]parser
+
+	// the order in which to try registered parsers and the interface fallbacks when looking for a
+	// way to parse a field.
+	strategies []Strategy
+
+	// variables with these prefixes must all be used by some field.
+	strictPrefixes []string
+
+	// if set, a variable with this suffix names a file to read a missing variable's value from.
+	fileSuffix string
+
+	// like parsers, but for turning values back into strings.
+	formatters map[parserKey]formatter
+
+	// the flag sets bound with BindFlags, whose flags override all other sources.  flagMu guards
+	// them, since flags can be bound while another goroutine loads config.
+	flagMu       sync.Mutex
+	flagBindings []*flagBinding
+
+	// the report from the latest load into a struct of each type, for Dump.
+	reportMu sync.Mutex
+	reports  map[reflect.Type]loadedReport
+}
+
+// loadedReport is a Report and the config struct it's for.
+type loadedReport struct {
+	c      interface{}
+	report *Report
 }
 
 // RegisterParser takes a func (string) (<anytype>, error) and registers it on the Loader as
@@ -71,7 +124,7 @@ func (e *Loader) RegisterParser(f interface{}) error {
 		return fmt.Errorf("envcfg: %v is not a func", f)
 	}
 
-	fname := runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
+	fname := funcName(f)
 	// f should accept at least one argument
 	if t.NumIn() < 1 {
 		return fmt.Errorf(
@@ -129,14 +182,25 @@ func (e *Loader) RegisterParser(f interface{}) error {
 		}
 		returnvals := callable.Call(vals)
 		if !returnvals[1].IsNil() {
-			return reflect.Value{}, fmt.Errorf("%v", returnvals[1])
+			return reflect.Value{}, returnvals[1].Interface().(error)
 		}
 		return returnvals[0], nil
 	}
-	e.parsers[key] = parser{f: wrapped, numArgs: t.NumIn()}
+	e.parsers[key] = parser{f: wrapped, numArgs: t.NumIn(), name: shortFuncName(fname)}
 	return nil
 }
 
+// funcName returns the full name of the func f, including its package path.
+func funcName(f interface{}) string {
+	return runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
+}
+
+// shortFuncName trims the package path from a func name like github.com/nav-inc/envcfg.ParseInt8,
+// leaving envcfg.ParseInt8.
+func shortFuncName(fname string) string {
+	return fname[strings.LastIndex(fname, "/")+1:]
+}
+
 // MustRegisterParser attempts to register the provided parser func and panics if it gets an error.
 func (e *Loader) MustRegisterParser(f interface{}) {
 	if err := e.RegisterParser(f); err != nil {
@@ -144,52 +208,95 @@ func (e *Loader) MustRegisterParser(f interface{}) {
 	}
 }
 
-// loadStructFields is a helper function that recursively loads values into struct fields
-func (e *Loader) loadStructFields(vals map[string]string, structType reflect.Type, structVal reflect.Value) error {
-	var errs *multierror.Error
+// loadStructFields is a helper function that recursively loads values into struct fields.  The
+// prefix is prepended to every variable name in the struct's tags, and the path is the struct's
+// own place in the config, used to name its fields in errors.
+func (e *Loader) loadStructFields(
+	st *loadState,
+	prefix string,
+	path string,
+	structType reflect.Type,
+	structVal reflect.Value,
+) error {
+	var errs LoadErrors
+
+	st.loading[structType] = true
+	defer delete(st.loading, structType)
 
 	for i := 0; i < structType.NumField(); i++ {
 		field := structType.Field(i)
+		fieldPath := joinPath(path, field.Name)
 
-		// If this is an embedded struct field with no explicit field name, recurse into it
-		if field.Anonymous && field.Type.Kind() == reflect.Struct && field.Name == field.Type.Name() {
-			err := e.loadStructFields(vals, field.Type, structVal.Field(i))
+		tagVal, ok := field.Tag.Lookup(cfgTag)
+
+		// If this is an embedded struct field with no explicit field name, or a named struct field
+		// without our tag, recurse into it
+		if isNestedStruct(field, ok) {
+			var err error
+			if field.Type.Kind() == reflect.Ptr {
+				err = e.loadStructPtr(st, prefix+field.Tag.Get(prefixTag), field, fieldPath, structVal.Field(i))
+			} else {
+				err = e.loadStructFields(st, prefix+field.Tag.Get(prefixTag), fieldPath, field.Type, structVal.Field(i))
+			}
 			if err != nil {
-				errs = multierror.Append(errs, err)
+				errs = errs.append(err)
+			}
+			continue
+		}
+
+		// If this is a slice of structs without our tag, fill it from numbered variables
+		if isStructSlice(field, ok) {
+			err := e.loadStructSlice(st, prefix+field.Tag.Get(prefixTag), field, fieldPath, structVal.Field(i))
+			if err != nil {
+				errs = errs.append(err)
+			}
+			continue
+		}
+
+		// If this is a map of structs without our tag, fill it from named groups of variables
+		if isStructMap(field, ok) {
+			err := e.loadStructMap(st, prefix+field.Tag.Get(prefixTag), field, fieldPath, structVal.Field(i))
+			if err != nil {
+				errs = errs.append(err)
 			}
 			continue
 		}
 
-		tagVal, ok := field.Tag.Lookup(cfgTag)
 		if !ok {
 			// this field doesn't have our tag. Skip.
 			continue
 		}
 
 		envKeys := strings.Split(tagVal, tagSep)
+		for j := range envKeys {
+			envKeys[j] = prefix + envKeys[j]
+		}
 		var envDefaults []string
 
 		defaultString, defaultOK := field.Tag.Lookup(defaultTag)
 		if defaultOK {
 			envDefaults = splitDefaultTag(defaultString)
 			if len(envKeys) != len(envDefaults) {
-				return fmt.Errorf("envcfg: env tag %s has %d names but default tag %s has %d values",
-					tagVal, len(envKeys),
-					defaultString, len(envDefaults),
-				)
+				return &TagMismatchError{Field: fieldPath, EnvTag: tagVal, DefaultTag: defaultString}
 			}
 		}
 
-		key := parserKey{
-			typ:     field.Type,
-			numArgs: len(envKeys),
+		opts, err := parseFieldOptions(field, fieldPath)
+		if err != nil {
+			return err
+		}
+
+		fileSuffix, err := e.fieldFileSuffix(field, fieldPath)
+		if err != nil {
+			return err
+		}
+
+		parser, ok, err := e.fieldParser(field, fieldPath, len(envKeys))
+		if err != nil {
+			return err
 		}
-		parser, ok := e.parsers[key]
 		if !ok {
-			errs = multierror.Append(
-				errs,
-				fmt.Errorf("no parser function found for type %v (field %s)", field.Type, field.Name),
-			)
+			errs = errs.append(&NoParserError{Field: fieldPath, Type: field.Type, NumArgs: len(envKeys)})
 			continue
 		}
 
@@ -203,64 +310,151 @@ func (e *Loader) loadStructFields(vals map[string]string, structType reflect.Typ
 		}
 
 		stringVals := []string{}
-		shouldParse := true
+		report := FieldReport{Path: fieldPath, Parser: parser.name, Secret: opts.secret}
+		var missing []error
+		anyFound := false
 		for i, envKey := range envKeys {
-			stringVal, ok := vals[envKey]
-			if !ok {
-				// could not find the string we're looking for in map. is there a default?
-				if defaultOK {
-					stringVal = envDefaults[i]
-				} else {
-					errs = multierror.Append(
-						errs,
-						fmt.Errorf("no %s value found, and %s.%s has no default", envKey, structType.Name(), field.Name),
-					)
-					// set the shouldParse flag to false if there was a problem, but continue checking the
-					// rest of the variables so we can show all the missing ones at once.
-					shouldParse = false
+			v := VarReport{Key: envKey}
+			stringVal, source, ok := st.lookup(envKey)
+			var fileErr error
+			if ok {
+				v.Origin, v.Source = st.origin(source)
+			} else if fileSuffix != "" {
+				v.FileKey = envKey + fileSuffix
+				stringVal, ok, fileErr = st.lookupFile(fieldPath, v.FileKey)
+				if ok {
+					v.Origin = OriginFile
+				}
+			}
+			switch {
+			case fileErr != nil:
+				// the file variable is set, so this counts as found even if the field is optional.
+				anyFound = true
+				missing = append(missing, fileErr)
+			case ok:
+				anyFound = true
+			case defaultOK:
+				// could not find the string we're looking for in map, but there's a default.
+				stringVal = envDefaults[i]
+				v.Origin = OriginDefault
+				if st.report != nil {
+					// the default might be in use because the variable's name is misspelled.
+					v.Suggestion = st.suggest(envKey, prefixedKeys(prefix, structType))
 				}
+			default:
+				// keep checking the rest of the variables so we can show all the missing ones at
+				// once.
+				v.Suggestion = st.suggest(envKey, prefixedKeys(prefix, structType))
+				missing = append(missing, &MissingValueError{
+					Field:      fieldPath,
+					EnvKey:     envKey,
+					Suggestion: v.Suggestion,
+				})
 			}
+			if v.Origin != OriginUnset && opts.notEmpty && stringVal == "" {
+				missing = append(missing, &EmptyValueError{Field: fieldPath, EnvKey: envKey})
+			}
+			v.Value = stringVal
 			stringVals = append(stringVals, stringVal)
+			report.Vars = append(report.Vars, v)
+		}
+		st.record(report)
+		if opts.optional && !anyFound && !defaultOK {
+			// none of this optional field's variables were set. Leave it alone.
+			continue
 		}
 		// if we got an error reading any of the variables needed by this parser, then don't bother
 		// calling the parser
-		if !shouldParse {
+		if len(missing) > 0 {
+			errs = errs.append(missing...)
 			continue
 		}
 
 		toSet, err := parser.f(stringVals...)
 		if err != nil {
-			errs = multierror.Append(
-				errs,
-				fmt.Errorf("envcfg: cannot populate %s: %v", field.Name, err),
-			)
+			errs = errs.append(&ParseError{
+				Field:   fieldPath,
+				EnvKeys: envKeys,
+				Values:  stringVals,
+				Err:     err,
+			})
 			continue
 		}
 		structVal.Field(i).Set(toSet)
 	}
-	return errs.ErrorOrNil()
+	return errs.orNil()
 }
 
-// LoadFromMap loads config from the provided map into the provided struct.
-func (e *Loader) LoadFromMap(vals map[string]string, c interface{}) error {
-	// assert that c is a struct.
+// configType returns the type of the struct that c points to, or an error if c isn't a pointer to a
+// struct.
+func configType(c interface{}) (reflect.Type, error) {
 	pointerType := reflect.TypeOf(c)
-	if pointerType.Kind() != reflect.Ptr {
-		return fmt.Errorf("envcfg: %v is not a pointer", c)
+	if pointerType == nil || pointerType.Kind() != reflect.Ptr {
+		return nil, fmt.Errorf("envcfg: %v is not a pointer", c)
 	}
-
 	structType := pointerType.Elem()
 	if structType.Kind() != reflect.Struct {
-		return fmt.Errorf("envcfg: %v is not a pointer to a struct", c)
+		return nil, fmt.Errorf("envcfg: %v is not a pointer to a struct", c)
+	}
+	return structType, nil
+}
+
+// joinPath adds a field name to the path of the struct that holds it.
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// LoadFrom loads config from the provided sources into the provided struct.  Each variable is read
+// from the first source that has it, so sources should be given highest priority first.  The
+// default tags come after all of the sources.
+func (e *Loader) LoadFrom(c interface{}, sources ...Source) error {
+	return e.loadFrom(c, sources, nil)
+}
+
+// loadFrom does the work of LoadFrom, adding each field to report, or to a new report if it's nil.
+// The report is kept for Dump.
+func (e *Loader) loadFrom(c interface{}, sources []Source, report *Report) error {
+	structType, err := configType(c)
+	if err != nil {
+		return err
 	}
 	structVal := reflect.ValueOf(c).Elem()
+	if report == nil {
+		report = &Report{}
+	}
+	defer func() {
+		e.reportMu.Lock()
+		defer e.reportMu.Unlock()
+		e.reports[structType] = loadedReport{c: c, report: report}
+	}()
 
-	return e.loadStructFields(vals, structType, structVal)
+	flags := e.flagSources()
+	st := newLoadState(append(flags, sources...), structType)
+	st.numFlags = len(flags)
+	st.report = report
+	err = e.loadStructFields(st, "", structType.Name(), structType, structVal)
+	if len(e.strictPrefixes) == 0 {
+		return err
+	}
+	errs, ok := err.(LoadErrors)
+	if err != nil && !ok {
+		// something's wrong with the struct itself. Don't bother looking for unused variables.
+		return err
+	}
+	return errs.append(st.unused(e.strictPrefixes)...).orNil()
+}
+
+// LoadFromMap loads config from the provided map into the provided struct.
+func (e *Loader) LoadFromMap(vals map[string]string, c interface{}) error {
+	return e.LoadFrom(c, MapSource(vals))
 }
 
 // Load loads config from the environment into the provided struct.
 func (e *Loader) Load(c interface{}) error {
-	return e.LoadFromMap(envListToMap(os.Environ()), c)
+	return e.LoadFrom(c, EnvSource())
 }
 
 func envListToMap(ss []string) map[string]string {
@@ -277,11 +471,16 @@ func envListToMap(ss []string) map[string]string {
 }
 
 func splitDefaultTag(tag string) []string {
+	return splitEscaped(tag, ',')
+}
+
+// splitEscaped splits s on sep, treating a backslash-escaped sep as part of the current substring.
+func splitEscaped(s string, sep rune) []string {
 	out := []string{}
 	var lastChar rune
 	var subString string
-	for _, char := range tag {
-		if string(char) == tagSep {
+	for _, char := range s {
+		if char == sep {
 			if lastChar == backSlash {
 				// escaped separator. Remove the escape, and make the separator part of the subString
 				subString = subString[:len(subString)-1]
@@ -1,12 +1,17 @@
 package envcfg
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"html/template"
+	"math/big"
 	"net"
 	"net/mail"
 	"net/url"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -179,12 +184,12 @@ func TestBuggyParsers(t *testing.T) {
 		{
 			desc:   "parser that errors",
 			parser: func(s string) (foo, error) { return foo{}, errors.New("oops") },
-			err:    "1 error occurred:\n\n* envcfg: cannot populate B: oops",
+			err:    "envcfg: cannot populate myConfig.B: oops",
 		},
 		{
 			desc:   "parser that panics",
 			parser: func(s string) (foo, error) { panic("I panicked") },
-			err:    "1 error occurred:\n\n* envcfg: cannot populate B: github.com/nav-inc/envcfg.TestBuggyParsers.func2 panicked: I panicked",
+			err:    "envcfg: cannot populate myConfig.B: github.com/nav-inc/envcfg.TestBuggyParsers.func2 panicked: I panicked",
 		},
 	}
 
@@ -266,7 +271,7 @@ func TestMismatchedParserAndTags(t *testing.T) {
 	}, &conf)
 	assert.Equal(
 		t,
-		"1 error occurred:\n\n* no parser function found for type envcfg.myString (field F)",
+		"no parser function found for type envcfg.myString (field myConfig.F)",
 		err.Error(),
 	)
 }
@@ -287,9 +292,10 @@ func TestMismatchedDefaults(t *testing.T) {
 	}, &conf)
 	assert.Equal(
 		t,
-		errors.New("envcfg: env tag A,B,C has 3 names but default tag X,Y has 2 values"),
+		&TagMismatchError{Field: "myConfig.F", EnvTag: "A,B,C", DefaultTag: "X,Y"},
 		err,
 	)
+	assert.Equal(t, "envcfg: env tag A,B,C has 3 names but default tag X,Y has 2 values", err.Error())
 }
 
 func TestDefaultsCommaEscape(t *testing.T) {
@@ -318,7 +324,7 @@ func TestMissingValue(t *testing.T) {
 
 	var conf myConfig
 	err := LoadFromMap(map[string]string{}, &conf)
-	assert.Equal(t, "1 error occurred:\n\n* no FOO3 value found, and myConfig.F has no default", err.Error())
+	assert.Equal(t, "no FOO3 value found, and myConfig.F has no default", err.Error())
 }
 
 func TestBadStructs(t *testing.T) {
@@ -345,7 +351,7 @@ func TestBadStructs(t *testing.T) {
 		{
 			desc:  "no parser for this type",
 			strct: &quux{},
-			err:   "1 error occurred:\n\n* no parser function found for type envcfg.baz (field B)",
+			err:   "no parser function found for type envcfg.baz (field quux.B)",
 		},
 	}
 
@@ -424,7 +430,7 @@ func TestNestedStructs(t *testing.T) {
 				"DB_HOST": "localhost",
 				"API_KEY": "secret123",
 			},
-			errMsg: "2 errors occurred:\n\n* no DB_PORT value found, and DatabaseConfig.Port has no default\n* no LOG_LEVEL value found, and LogConfig.Level has no default",
+			errMsg: "no DB_PORT value found, and Config.DatabaseConfig.Port has no default\nno LOG_LEVEL value found, and Config.LogConfig.Level has no default",
 		},
 	}
 
@@ -492,7 +498,658 @@ func TestNestedStructEdgeCases(t *testing.T) {
 		assert.Equal(t, "value1", conf.GrandchildConfig.Setting)
 		assert.Equal(t, "value2", conf.ChildSetting)
 		assert.Equal(t, "value3", conf.Direct)
-		// Named field should not be populated
-		assert.Equal(t, "", conf.Named.ChildSetting)
+		// Named field is populated from the same variables
+		assert.Equal(t, "value1", conf.Named.GrandchildConfig.Setting)
+		assert.Equal(t, "value2", conf.Named.ChildSetting)
 	})
+
+	// Test named struct fields with prefixes
+	type PrefixedConfig struct {
+		ChildConfig `envPrefix:"EMBEDDED_"`
+		Primary     ChildConfig `envPrefix:"PRIMARY_"`
+		Replica     ChildConfig `envPrefix:"REPLICA_"`
+		unexported  ChildConfig
+	}
+
+	t.Run("prefixed named structs", func(t *testing.T) {
+		var conf PrefixedConfig
+		input := map[string]string{
+			"EMBEDDED_GRANDCHILD_SETTING": "value1",
+			"EMBEDDED_CHILD_SETTING":      "value2",
+			"PRIMARY_GRANDCHILD_SETTING":  "value3",
+			"PRIMARY_CHILD_SETTING":       "value4",
+			"REPLICA_GRANDCHILD_SETTING":  "value5",
+		}
+
+		err := LoadFromMap(input, &conf)
+		assert.Equal(t, "no REPLICA_CHILD_SETTING value found, and PrefixedConfig.Replica.ChildSetting has no default", err.Error())
+		assert.Equal(t, "value1", conf.GrandchildConfig.Setting)
+		assert.Equal(t, "value2", conf.ChildSetting)
+		assert.Equal(t, "value3", conf.Primary.GrandchildConfig.Setting)
+		assert.Equal(t, "value4", conf.Primary.ChildSetting)
+		assert.Equal(t, "value5", conf.Replica.GrandchildConfig.Setting)
+		assert.Equal(t, ChildConfig{}, conf.unexported)
+	})
+}
+
+type logLevel int
+
+func (l *logLevel) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "debug":
+		*l = 0
+	case "info":
+		*l = 1
+	default:
+		return fmt.Errorf("unknown log level %q", text)
+	}
+	return nil
+}
+
+func TestTextUnmarshaler(t *testing.T) {
+	type myConfig struct {
+		Level logLevel `env:"LOG_LEVEL"`
+		Big   *big.Int `env:"BIG"`
+	}
+
+	t.Run("value and pointer types", func(t *testing.T) {
+		var conf myConfig
+		err := LoadFromMap(map[string]string{
+			"LOG_LEVEL": "info",
+			"BIG":       "123456789012345678901234567890",
+		}, &conf)
+		assert.Nil(t, err)
+		expected, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+		assert.Equal(t, logLevel(1), conf.Level)
+		assert.Equal(t, expected, conf.Big)
+	})
+
+	t.Run("unmarshal error", func(t *testing.T) {
+		var conf myConfig
+		err := LoadFromMap(map[string]string{
+			"LOG_LEVEL": "loud",
+			"BIG":       "1",
+		}, &conf)
+		assert.Equal(t, "envcfg: cannot populate myConfig.Level: unknown log level \"loud\"", err.Error())
+	})
+
+	t.Run("registered parser wins", func(t *testing.T) {
+		ec, _ := New()
+		ec.MustRegisterParser(func(s string) (logLevel, error) { return logLevel(len(s)), nil })
+		var conf myConfig
+		err := ec.LoadFromMap(map[string]string{
+			"LOG_LEVEL": "debug",
+			"BIG":       "1",
+		}, &conf)
+		assert.Nil(t, err)
+		assert.Equal(t, logLevel(5), conf.Level)
+	})
+}
+
+// csvFlag implements flag.Value, and also json.Unmarshaler so we can see which one wins.
+type csvFlag []string
+
+func (c *csvFlag) String() string { return strings.Join(*c, ",") }
+
+func (c *csvFlag) Set(s string) error {
+	*c = strings.Split(s, ",")
+	return nil
+}
+
+func (c *csvFlag) UnmarshalJSON(b []byte) error {
+	var ss []string
+	if err := json.Unmarshal(b, &ss); err != nil {
+		return err
+	}
+	*c = ss
+	return nil
+}
+
+// rawBytes implements only encoding.BinaryUnmarshaler.
+type rawBytes struct {
+	b []byte
+}
+
+func (r *rawBytes) UnmarshalBinary(b []byte) error {
+	r.b = append([]byte{}, b...)
+	return nil
+}
+
+func TestResolutionOrder(t *testing.T) {
+	type myConfig struct {
+		Names csvFlag  `env:"NAMES"`
+		Raw   rawBytes `env:"RAW"`
+	}
+
+	tt := []struct {
+		desc     string
+		opts     []Option
+		names    string
+		expected csvFlag
+		err      string
+	}{
+		{
+			desc:     "flag.Value before json.Unmarshaler by default",
+			names:    "a,b",
+			expected: csvFlag{"a", "b"},
+		},
+		{
+			desc:     "json.Unmarshaler first",
+			opts:     []Option{ResolutionOrder(JSONUnmarshalers, FlagValues, BinaryUnmarshalers)},
+			names:    `["a,b"]`,
+			expected: csvFlag{"a,b"},
+		},
+		{
+			desc:  "strategy left out",
+			opts:  []Option{ResolutionOrder(ParserFuncs, FlagValues)},
+			names: "a,b",
+			err:   "no parser function found for type envcfg.rawBytes (field myConfig.Raw)",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.desc, func(t *testing.T) {
+			ec, err := New(tc.opts...)
+			assert.Nil(t, err)
+			var conf myConfig
+			err = ec.LoadFromMap(map[string]string{"NAMES": tc.names, "RAW": "\x00\x01"}, &conf)
+			if tc.err != "" {
+				assert.Equal(t, tc.err, err.Error())
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expected, conf.Names)
+			assert.Equal(t, []byte{0, 1}, conf.Raw.b)
+		})
+	}
+}
+
+func TestSlices(t *testing.T) {
+	type myConfig struct {
+		Strings   []string        `env:"STRINGS"`
+		Ints      []int           `env:"INTS" default:"1\\,2\\,3"`
+		Durations []time.Duration `env:"DURATIONS" sep:";"`
+		URLs      []*url.URL      `env:"URLS" sep:" "`
+		Levels    []logLevel      `env:"LEVELS" default:""`
+	}
+
+	t.Run("success", func(t *testing.T) {
+		var conf myConfig
+		err := LoadFromMap(map[string]string{
+			"STRINGS":   "a,b\\,c,",
+			"DURATIONS": "1s;2m",
+			"URLS":      "http://a.example.com https://b.example.com",
+		}, &conf)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"a", "b,c", ""}, conf.Strings)
+		assert.Equal(t, []int{1, 2, 3}, conf.Ints)
+		assert.Equal(t, []time.Duration{time.Second, 2 * time.Minute}, conf.Durations)
+		assert.Equal(t, []*url.URL{
+			{Scheme: "http", Host: "a.example.com"},
+			{Scheme: "https", Host: "b.example.com"},
+		}, conf.URLs)
+		assert.Equal(t, []logLevel{}, conf.Levels)
+	})
+
+	t.Run("bad element", func(t *testing.T) {
+		var conf myConfig
+		err := LoadFromMap(map[string]string{
+			"STRINGS":   "",
+			"INTS":      "1,2,three",
+			"DURATIONS": "1s",
+			"URLS":      "",
+		}, &conf)
+		assert.Equal(
+			t,
+			"envcfg: cannot populate myConfig.Ints: element 2: strconv.Atoi: parsing \"three\": invalid syntax",
+			err.Error(),
+		)
+	})
+
+	t.Run("bad separator", func(t *testing.T) {
+		var conf struct {
+			Ints []int `env:"INTS" sep:"::"`
+		}
+		err := LoadFromMap(map[string]string{"INTS": "1::2"}, &conf)
+		assert.Equal(t, `envcfg: sep tag on field Ints should be a single character, not "::"`, err.Error())
+	})
+}
+
+func TestMaps(t *testing.T) {
+	type myConfig struct {
+		Labels   map[string]string        `env:"LABELS" default:"q=a=b"`
+		Counts   map[string]int           `env:"COUNTS"`
+		Timeouts map[string]time.Duration `env:"TIMEOUTS" sep:";" kvsep:":" default:"read:1s;write:2s"`
+		Empty    map[int]bool             `env:"EMPTY" default:""`
+	}
+
+	tt := []struct {
+		desc     string
+		vals     map[string]string
+		expected myConfig
+		err      string
+	}{
+		{
+			desc: "success",
+			vals: map[string]string{"COUNTS": "a=1,b=2,c\\=d=3"},
+			expected: myConfig{
+				Labels:   map[string]string{"q": "a=b"},
+				Counts:   map[string]int{"a": 1, "b": 2, "c=d": 3},
+				Timeouts: map[string]time.Duration{"read": time.Second, "write": 2 * time.Second},
+				Empty:    map[int]bool{},
+			},
+		},
+		{
+			desc: "bad value",
+			vals: map[string]string{"COUNTS": "", "TIMEOUTS": "a:1s;b:soon"},
+			err:  "envcfg: cannot populate myConfig.Timeouts: value for key \"b\": time: invalid duration \"soon\"",
+		},
+		{
+			desc: "missing separator",
+			vals: map[string]string{"COUNTS": "a=1,b"},
+			err:  "envcfg: cannot populate myConfig.Counts: pair 1 (\"b\") has no '=' separator",
+		},
+		{
+			desc: "duplicate key",
+			vals: map[string]string{"COUNTS": "a=1,a=2"},
+			err:  "envcfg: cannot populate myConfig.Counts: duplicate key \"a\"",
+		},
+		{
+			desc: "bad key",
+			vals: map[string]string{"COUNTS": "", "EMPTY": "1=true,x=false"},
+			err:  "envcfg: cannot populate myConfig.Empty: key \"x\": strconv.Atoi: parsing \"x\": invalid syntax",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.desc, func(t *testing.T) {
+			var conf myConfig
+			err := LoadFromMap(tc.vals, &conf)
+			if tc.err != "" {
+				assert.Equal(t, tc.err, err.Error())
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expected, conf)
+		})
+	}
+}
+
+func TestStructPointers(t *testing.T) {
+	type SentryConfig struct {
+		DSN         string `env:"SENTRY_DSN"`
+		Environment string `env:"SENTRY_ENVIRONMENT" default:"production"`
+	}
+
+	type KafkaConfig struct {
+		Brokers []string `env:"BROKERS"`
+		Topic   string   `env:"TOPIC"`
+	}
+
+	type Config struct {
+		Sentry   *SentryConfig `optional:"true"`
+		Kafka    *KafkaConfig  `envPrefix:"KAFKA_" optional:"true"`
+		Required *KafkaConfig  `envPrefix:"REQUIRED_"`
+		URL      *url.URL
+	}
+
+	tt := []struct {
+		desc     string
+		input    map[string]string
+		expected Config
+		errMsg   string
+	}{
+		{
+			desc: "optional sections left out",
+			input: map[string]string{
+				"REQUIRED_BROKERS": "a,b",
+				"REQUIRED_TOPIC":   "events",
+			},
+			expected: Config{
+				Required: &KafkaConfig{Brokers: []string{"a", "b"}, Topic: "events"},
+			},
+		},
+		{
+			desc: "optional sections set",
+			input: map[string]string{
+				"SENTRY_DSN":       "https://sentry.example.com/1",
+				"KAFKA_BROKERS":    "c",
+				"KAFKA_TOPIC":      "logs",
+				"REQUIRED_BROKERS": "a,b",
+				"REQUIRED_TOPIC":   "events",
+			},
+			expected: Config{
+				Sentry:   &SentryConfig{DSN: "https://sentry.example.com/1", Environment: "production"},
+				Kafka:    &KafkaConfig{Brokers: []string{"c"}, Topic: "logs"},
+				Required: &KafkaConfig{Brokers: []string{"a", "b"}, Topic: "events"},
+			},
+		},
+		{
+			desc: "partially set optional section and missing required section",
+			input: map[string]string{
+				"KAFKA_TOPIC": "logs",
+			},
+			errMsg: "no KAFKA_BROKERS value found, and Config.Kafka.Brokers has no default\nno REQUIRED_BROKERS value found, and Config.Required.Brokers has no default\nno REQUIRED_TOPIC value found, and Config.Required.Topic has no default",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.desc, func(t *testing.T) {
+			var conf Config
+			err := LoadFromMap(tc.input, &conf)
+			if tc.errMsg != "" {
+				assert.Equal(t, tc.errMsg, err.Error())
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expected, conf)
+		})
+	}
+
+	t.Run("struct errors in an optional section", func(t *testing.T) {
+		type BadConfig struct {
+			Name   string   `env:"NAME"`
+			Events chan int `env:"EVENTS"`
+		}
+		type Config struct {
+			Bad *BadConfig `envPrefix:"BAD_" optional:"true"`
+		}
+		var conf Config
+		err := LoadFromMap(map[string]string{}, &conf)
+		assert.Equal(t, "no parser function found for type chan int (field Config.Bad.Events)", err.Error())
+		assert.Nil(t, conf.Bad)
+	})
+
+	t.Run("self-referential type", func(t *testing.T) {
+		type Node struct {
+			Name string `env:"NAME"`
+			Next *Node  `envPrefix:"NEXT_"`
+		}
+		var conf Node
+		err := LoadFromMap(map[string]string{"NAME": "a"}, &conf)
+		assert.Equal(t, "envcfg: cannot load field Node.Next: envcfg.Node refers to itself", err.Error())
+	})
+}
+
+func TestStructSlices(t *testing.T) {
+	type UpstreamConfig struct {
+		Host string `env:"HOST"`
+		Port int    `env:"PORT" default:"80"`
+	}
+
+	type Config struct {
+		Upstreams []UpstreamConfig `envPrefix:"UPSTREAM_"`
+		Backups   []UpstreamConfig `envPrefix:"BACKUP_"`
+	}
+
+	t.Run("success", func(t *testing.T) {
+		var conf Config
+		err := LoadFromMap(map[string]string{
+			"UPSTREAM_0_HOST": "a.example.com",
+			"UPSTREAM_0_PORT": "8080",
+			"UPSTREAM_1_HOST": "b.example.com",
+			// index 2 is missing, so this one isn't read
+			"UPSTREAM_3_HOST": "d.example.com",
+		}, &conf)
+		assert.Nil(t, err)
+		assert.Equal(t, Config{
+			Upstreams: []UpstreamConfig{
+				{Host: "a.example.com", Port: 8080},
+				{Host: "b.example.com", Port: 80},
+			},
+		}, conf)
+	})
+
+	t.Run("errors in several elements", func(t *testing.T) {
+		var conf Config
+		err := LoadFromMap(map[string]string{
+			"UPSTREAM_0_PORT": "8080",
+			"UPSTREAM_1_HOST": "b.example.com",
+			"UPSTREAM_1_PORT": "eighty",
+		}, &conf)
+		assert.Equal(t, "no UPSTREAM_0_HOST value found, and Config.Upstreams[0].Host has no default\nenvcfg: cannot populate Config.Upstreams[1].Port: strconv.Atoi: parsing \"eighty\": invalid syntax", err.Error())
+	})
+}
+
+func TestStructMaps(t *testing.T) {
+	type DatabaseConfig struct {
+		Host string `env:"HOST"`
+		Port int    `env:"PORT" default:"5432"`
+	}
+
+	type TenantConfig struct {
+		Name string         `env:"NAME"`
+		DB   DatabaseConfig `envPrefix:"DB_"`
+	}
+
+	type Config struct {
+		Tenants map[string]TenantConfig `envPrefix:"TENANT_"`
+	}
+
+	t.Run("success", func(t *testing.T) {
+		var conf Config
+		err := LoadFromMap(map[string]string{
+			"TENANT_ACME_NAME":       "Acme Corp",
+			"TENANT_ACME_DB_HOST":    "acme.db",
+			"TENANT_BIG_CO_NAME":     "Big Co",
+			"TENANT_BIG_CO_DB_HOST":  "bigco.db",
+			"TENANT_BIG_CO_DB_PORT":  "6543",
+			"TENANT_UNKNOWN_SETTING": "ignored",
+		}, &conf)
+		assert.Nil(t, err)
+		assert.Equal(t, Config{
+			Tenants: map[string]TenantConfig{
+				"ACME":   {Name: "Acme Corp", DB: DatabaseConfig{Host: "acme.db", Port: 5432}},
+				"BIG_CO": {Name: "Big Co", DB: DatabaseConfig{Host: "bigco.db", Port: 6543}},
+			},
+		}, conf)
+	})
+
+	t.Run("errors in several entries", func(t *testing.T) {
+		var conf Config
+		err := LoadFromMap(map[string]string{
+			"TENANT_ACME_NAME":      "Acme Corp",
+			"TENANT_BIG_CO_NAME":    "Big Co",
+			"TENANT_BIG_CO_DB_HOST": "bigco.db",
+			"TENANT_BIG_CO_DB_PORT": "lots",
+		}, &conf)
+		assert.Equal(t, "no TENANT_ACME_DB_HOST value found, and Config.Tenants[ACME].DB.Host has no default\nenvcfg: cannot populate Config.Tenants[BIG_CO].DB.Port: strconv.Atoi: parsing \"lots\": invalid syntax", err.Error())
+	})
+
+	t.Run("no entries", func(t *testing.T) {
+		var conf Config
+		err := LoadFromMap(map[string]string{}, &conf)
+		assert.Nil(t, err)
+		assert.Nil(t, conf.Tenants)
+	})
+}
+
+func TestOptionalAndNotEmpty(t *testing.T) {
+	type myConfig struct {
+		Optional    string        `env:"OPTIONAL" optional:"true"`
+		OptionalDur time.Duration `env:"OPTIONAL_DUR" optional:"true" default:"1m"`
+		NotEmpty    string        `env:"NOT_EMPTY" notempty:"true"`
+		Both        []string      `env:"BOTH" optional:"true" notempty:"true"`
+	}
+
+	tt := []struct {
+		desc     string
+		input    map[string]string
+		expected myConfig
+		errMsg   string
+	}{
+		{
+			desc:     "optional fields absent",
+			input:    map[string]string{"NOT_EMPTY": "x"},
+			expected: myConfig{OptionalDur: time.Minute, NotEmpty: "x"},
+		},
+		{
+			desc:     "optional fields present",
+			input:    map[string]string{"OPTIONAL": "", "NOT_EMPTY": "x", "BOTH": "a,b"},
+			expected: myConfig{OptionalDur: time.Minute, NotEmpty: "x", Both: []string{"a", "b"}},
+		},
+		{
+			desc:   "empty values",
+			input:  map[string]string{"NOT_EMPTY": "", "BOTH": ""},
+			errMsg: "NOT_EMPTY value is empty, but myConfig.NotEmpty must not be empty\nBOTH value is empty, but myConfig.Both must not be empty",
+		},
+		{
+			desc:   "required field absent",
+			input:  map[string]string{},
+			errMsg: "no NOT_EMPTY value found, and myConfig.NotEmpty has no default",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.desc, func(t *testing.T) {
+			var conf myConfig
+			err := LoadFromMap(tc.input, &conf)
+			if tc.errMsg != "" {
+				assert.Equal(t, tc.errMsg, err.Error())
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expected, conf)
+		})
+	}
+
+	t.Run("bad tag", func(t *testing.T) {
+		var conf struct {
+			F string `env:"F" optional:"sometimes"`
+		}
+		err := LoadFromMap(map[string]string{}, &conf)
+		assert.Equal(t, `envcfg: optional tag on field F should be true or false, not "sometimes"`, err.Error())
+	})
+}
+
+func TestErrorTypes(t *testing.T) {
+	type myConfig struct {
+		Missing string        `env:"MISSING"`
+		Empty   string        `env:"EMPTY" notempty:"true"`
+		Bad     time.Duration `env:"BAD"`
+		Odd     struct{}      `env:"ODD"`
+	}
+
+	var conf myConfig
+	err := LoadFromMap(map[string]string{
+		"EMPTY": "",
+		"BAD":   "soon",
+		"ODD":   "?",
+	}, &conf)
+
+	var missing *MissingValueError
+	assert.True(t, errors.As(err, &missing))
+	assert.Equal(t, &MissingValueError{Field: "myConfig.Missing", EnvKey: "MISSING"}, missing)
+
+	var empty *EmptyValueError
+	assert.True(t, errors.As(err, &empty))
+	assert.Equal(t, &EmptyValueError{Field: "myConfig.Empty", EnvKey: "EMPTY"}, empty)
+
+	var parseErr *ParseError
+	assert.True(t, errors.As(err, &parseErr))
+	assert.Equal(t, "myConfig.Bad", parseErr.Field)
+	assert.Equal(t, []string{"BAD"}, parseErr.EnvKeys)
+	assert.Equal(t, []string{"soon"}, parseErr.Values)
+	assert.Equal(t, `time: invalid duration "soon"`, errors.Unwrap(parseErr).Error())
+
+	var noParser *NoParserError
+	assert.True(t, errors.As(err, &noParser))
+	assert.Equal(t, "myConfig.Odd", noParser.Field)
+	assert.Equal(t, reflect.TypeOf(struct{}{}), noParser.Type)
+
+	var loadErrs LoadErrors
+	assert.True(t, errors.As(err, &loadErrs))
+	assert.Len(t, loadErrs, 4)
+
+	var tagErr *InvalidTagError
+	err = LoadFromMap(map[string]string{}, &struct {
+		M map[string]string `env:"M" sep:"=" optional:"true"`
+	}{})
+	assert.True(t, errors.As(err, &tagErr))
+	assert.Equal(t, &InvalidTagError{Field: "M", Tag: "kvsep", Value: "=", Want: "different from the sep separator"}, tagErr)
+}
+
+func TestSuggestions(t *testing.T) {
+	type myConfig struct {
+		Password string `env:"DB_PASSWORD"`
+		Host     string `env:"DB_HOST"`
+		Port     int    `env:"DB_PORT"`
+		User     string `env:"DB_USER"`
+	}
+
+	var conf myConfig
+	err := LoadFromMap(map[string]string{
+		"DB_PASWORD":                     "hunter2",
+		"db_host":                        "localhost",
+		"DB_PORTS":                       "5432",
+		"DB_USERNAME_FOR_SOMETHING_ELSE": "admin",
+	}, &conf)
+	assert.Equal(
+		t,
+		"no DB_PASSWORD value found, and myConfig.Password has no default (did you mean DB_PASWORD?)\n"+
+			"no DB_HOST value found, and myConfig.Host has no default (did you mean db_host?)\n"+
+			"no DB_PORT value found, and myConfig.Port has no default (did you mean DB_PORTS?)\n"+
+			"no DB_USER value found, and myConfig.User has no default",
+		err.Error(),
+	)
+}
+
+func TestEditDistance(t *testing.T) {
+	tt := []struct {
+		a, b string
+		dist int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"", "abc", 3},
+		{"DB_PASSWORD", "DB_PASWORD", 1},
+		{"kitten", "sitting", 3},
+	}
+	for _, tc := range tt {
+		assert.Equal(t, tc.dist, editDistance(tc.a, tc.b), tc.a+" "+tc.b)
+	}
+}
+
+func TestStrictPrefix(t *testing.T) {
+	type UpstreamConfig struct {
+		Host string `env:"HOST"`
+	}
+	type myConfig struct {
+		Name      string           `env:"MYAPP_NAME"`
+		Debug     bool             `env:"MYAPP_DEBUG" default:"false"`
+		Upstreams []UpstreamConfig `envPrefix:"MYAPP_UPSTREAM_"`
+	}
+
+	vals := map[string]string{
+		"MYAPP_NAME":             "app",
+		"MYAPP_DEBUGG":           "true",
+		"MYAPP_UPSTREAM_0_HOST":  "a.example.com",
+		"MYAPP_UPSTREAM_0_PORT":  "80",
+		"OTHERAPP_SETTING":       "x",
+		"LEGACY_MYAPP_SETTING":   "y",
+		"MYAPP_INTERNAL_SETTING": "z",
+	}
+
+	ec, err := New(StrictPrefix("MYAPP_"))
+	assert.Nil(t, err)
+	var conf myConfig
+	err = ec.LoadFromMap(vals, &conf)
+	assert.Equal(
+		t,
+		"MYAPP_DEBUGG is set, but no field uses it\n"+
+			"MYAPP_INTERNAL_SETTING is set, but no field uses it\n"+
+			"MYAPP_UPSTREAM_0_PORT is set, but no field uses it",
+		err.Error(),
+	)
+	var unused *UnusedValueError
+	assert.True(t, errors.As(err, &unused))
+	assert.Equal(t, "MYAPP_DEBUGG", unused.EnvKey)
+
+	// the struct is still loaded
+	assert.Equal(t, "app", conf.Name)
+	assert.Equal(t, []UpstreamConfig{{Host: "a.example.com"}}, conf.Upstreams)
+
+	// without the option, the extra variables are ignored
+	err = LoadFromMap(vals, &conf)
+	assert.Nil(t, err)
 }
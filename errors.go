@@ -0,0 +1,184 @@
+package envcfg
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// this file holds the error types returned when loading config, so that callers can tell the
+// different kinds of problems apart with errors.As instead of matching message text.  Each error
+// names the field it's about by its full path from the struct passed to Load, like
+// Config.DatabaseConfig.Port, or Config.Upstreams[0].Host for elements of slices and maps.
+
+// LoadErrors is returned when one or more fields can't be loaded.  It holds one error per problem,
+// so that all of them can be fixed at once.
+type LoadErrors []error
+
+func (e LoadErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap returns the individual errors.
+func (e LoadErrors) Unwrap() []error {
+	return e
+}
+
+// As finds the first of the individual errors that matches target.  errors.As does this on its own
+// in Go 1.20 and later, thanks to Unwrap.
+func (e LoadErrors) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Is reports whether any of the individual errors matches target.  errors.Is does this on its own
+// in Go 1.20 and later, thanks to Unwrap.
+func (e LoadErrors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// append adds err to the list, flattening it if it's a LoadErrors itself.  Nil errors are ignored.
+func (e LoadErrors) append(errs ...error) LoadErrors {
+	for _, err := range errs {
+		if nested, ok := err.(LoadErrors); ok {
+			e = append(e, nested...)
+		} else if err != nil {
+			e = append(e, err)
+		}
+	}
+	return e
+}
+
+// orNil returns nil if there are no errors, so callers don't get a non-nil error interface holding
+// an empty list.
+func (e LoadErrors) orNil() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// MissingValueError is returned when a required variable isn't set and its field has no default.
+// If there's a variable with a very similar name, it's named in Suggestion.
+type MissingValueError struct {
+	Field      string
+	EnvKey     string
+	Suggestion string
+}
+
+func (e *MissingValueError) Error() string {
+	msg := fmt.Sprintf("no %s value found, and %s has no default", e.EnvKey, e.Field)
+	if e.Suggestion != "" {
+		msg += fmt.Sprintf(" (did you mean %s?)", e.Suggestion)
+	}
+	return msg
+}
+
+// EmptyValueError is returned when a field tagged notempty:"true" gets an empty value.
+type EmptyValueError struct {
+	Field  string
+	EnvKey string
+}
+
+func (e *EmptyValueError) Error() string {
+	return fmt.Sprintf("%s value is empty, but %s must not be empty", e.EnvKey, e.Field)
+}
+
+// FileReadError is returned when a variable like DB_PASSWORD_FILE names a file that can't be read.
+// See FileSuffix.
+type FileReadError struct {
+	Field  string
+	EnvKey string
+	Path   string
+	Err    error
+}
+
+func (e *FileReadError) Error() string {
+	return fmt.Sprintf("envcfg: cannot read file %s (from %s) for %s: %v", e.Path, e.EnvKey, e.Field, e.Err)
+}
+
+func (e *FileReadError) Unwrap() error {
+	return e.Err
+}
+
+// ParseError is returned when a field's parser fails.  Values holds the strings that were passed to
+// the parser, one per variable in EnvKeys.
+type ParseError struct {
+	Field   string
+	EnvKeys []string
+	Values  []string
+	Err     error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("envcfg: cannot populate %s: %v", e.Field, e.Err)
+}
+
+// Unwrap returns the error from the parser.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// NoParserError is returned when the Loader has no way to parse a field's type from the number of
+// variables in its env tag.
+type NoParserError struct {
+	Field   string
+	Type    reflect.Type
+	NumArgs int
+}
+
+func (e *NoParserError) Error() string {
+	return fmt.Sprintf("no parser function found for type %v (field %s)", e.Type, e.Field)
+}
+
+// TagMismatchError is returned when a field's default tag doesn't have one value for each of the
+// variables in its env tag.
+type TagMismatchError struct {
+	Field      string
+	EnvTag     string
+	DefaultTag string
+}
+
+func (e *TagMismatchError) Error() string {
+	return fmt.Sprintf("envcfg: env tag %s has %d names but default tag %s has %d values",
+		e.EnvTag, len(strings.Split(e.EnvTag, tagSep)),
+		e.DefaultTag, len(splitDefaultTag(e.DefaultTag)),
+	)
+}
+
+// UnusedValueError is returned by Loaders with the StrictPrefix option for each variable with a
+// strict prefix that none of the config struct's fields used.
+type UnusedValueError struct {
+	EnvKey string
+}
+
+func (e *UnusedValueError) Error() string {
+	return fmt.Sprintf("%s is set, but no field uses it", e.EnvKey)
+}
+
+// InvalidTagError is returned when one of a field's tags has a value that envcfg can't use.
+type InvalidTagError struct {
+	Field string
+	Tag   string
+	Value string
+	// what the tag's value should have been
+	Want string
+}
+
+func (e *InvalidTagError) Error() string {
+	return fmt.Sprintf("envcfg: %s tag on field %s should be %s, not %q", e.Tag, e.Field, e.Want, e.Value)
+}
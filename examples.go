@@ -0,0 +1,195 @@
+package envcfg
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// this file holds generators for example config files (.env files, Kubernetes manifests, and
+// docker-compose environment blocks), built from a config struct so they list every variable it
+// reads.
+//
+// Each field's variables are listed with their defaults, and variables without defaults are left
+// blank.  Optional variables without defaults, and variables in numbered lists and named groups of
+// structs, are commented out, since setting them to an empty string isn't the same as leaving them
+// unset.
+
+// exampleField is a field's worth of variables in an example file.
+type exampleField struct {
+	keys   []string
+	values []string
+	// lines to write in a comment above the variables.
+	comments []string
+	// the variables should be commented out.
+	commented bool
+	secret    bool
+}
+
+// exampleFields returns the fields of the config struct that c points to, for writing an example
+// file.
+func exampleFields(c interface{}) ([]exampleField, error) {
+	structType, err := configType(c)
+	if err != nil {
+		return nil, err
+	}
+	var out []exampleField
+	// each variable is listed once, for the first field that uses it, since a repeated key isn't
+	// valid YAML.
+	seen := map[string]bool{}
+	err = walkFields("", structType.Name(), structType, func(tf taggedField) error {
+		if err := tf.checkDefaults(); err != nil {
+			return err
+		}
+		ef := exampleField{
+			commented: tf.dynamic || (tf.optional && !tf.hasDefault),
+			secret:    tf.secret,
+		}
+		for i, key := range tf.keys {
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			ef.keys = append(ef.keys, key)
+			if tf.hasDefault {
+				ef.values = append(ef.values, tf.defaults[i])
+			} else {
+				ef.values = append(ef.values, "")
+			}
+		}
+		if len(ef.keys) == 0 {
+			return nil
+		}
+		if desc := tf.field.Tag.Get(descTag); desc != "" {
+			ef.comments = append(ef.comments, desc)
+		}
+		status := "required"
+		if tf.hasDefault || tf.optional {
+			status = "optional"
+		}
+		ef.comments = append(ef.comments, fmt.Sprintf("%v, %s", tf.field.Type, status))
+		out = append(out, ef)
+		return nil
+	})
+	return out, err
+}
+
+// WriteDotenvExample writes a commented .env file to w, listing each variable that c's fields are
+// loaded from, with its default (if any), type, and the text from the field's desc tag.  c must be
+// a pointer to a config struct, but only its type is used.
+func WriteDotenvExample(w io.Writer, c interface{}) error {
+	fields, err := exampleFields(c)
+	if err != nil {
+		return err
+	}
+	ew := &errWriter{w: w}
+	for i, ef := range fields {
+		if i > 0 {
+			ew.printf("\n")
+		}
+		for _, comment := range ef.comments {
+			ew.printf("# %s\n", comment)
+		}
+		for j, key := range ef.keys {
+			if ef.commented {
+				ew.printf("# ")
+			}
+			ew.printf("%s=%s\n", key, dotenvQuote(ef.values[j]))
+		}
+	}
+	return ew.err
+}
+
+// WriteKubernetesExample writes a Kubernetes ConfigMap named name to w, holding each variable that
+// c's fields are loaded from, with its default (if any), type, and the text from the field's desc
+// tag.  Fields tagged secret:"true" go in a Secret with the same name instead.  c must be a pointer
+// to a config struct, but only its type is used.
+func WriteKubernetesExample(w io.Writer, c interface{}, name string) error {
+	fields, err := exampleFields(c)
+	if err != nil {
+		return err
+	}
+	var config, secrets []exampleField
+	for _, ef := range fields {
+		if ef.secret {
+			secrets = append(secrets, ef)
+		} else {
+			config = append(config, ef)
+		}
+	}
+
+	ew := &errWriter{w: w}
+	ew.printf("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: %s\ndata:", yamlQuote(name))
+	writeYAMLFields(ew, config, "  ")
+	if len(secrets) > 0 {
+		ew.printf("---\napiVersion: v1\nkind: Secret\nmetadata:\n  name: %s\ntype: Opaque\nstringData:", yamlQuote(name))
+		writeYAMLFields(ew, secrets, "  ")
+	}
+	return ew.err
+}
+
+// WriteComposeExample writes a docker-compose environment block to w, holding each variable that
+// c's fields are loaded from, with its default (if any), type, and the text from the field's desc
+// tag.  c must be a pointer to a config struct, but only its type is used.
+func WriteComposeExample(w io.Writer, c interface{}) error {
+	fields, err := exampleFields(c)
+	if err != nil {
+		return err
+	}
+	ew := &errWriter{w: w}
+	ew.printf("environment:")
+	writeYAMLFields(ew, fields, "  ")
+	return ew.err
+}
+
+// writeYAMLFields finishes a line holding a YAML mapping's key by writing the mapping's entries, one
+// for each of the fields' variables, with the given indent.
+func writeYAMLFields(ew *errWriter, fields []exampleField, indent string) {
+	if len(fields) == 0 {
+		ew.printf(" {}\n")
+		return
+	}
+	ew.printf("\n")
+	for _, ef := range fields {
+		for _, comment := range ef.comments {
+			ew.printf("%s# %s\n", indent, comment)
+		}
+		for j, key := range ef.keys {
+			ew.printf("%s", indent)
+			if ef.commented {
+				ew.printf("# ")
+			}
+			ew.printf("%s: %s\n", key, yamlQuote(ef.values[j]))
+		}
+	}
+}
+
+// dotenvQuote quotes s, if needed, so that it reads back as itself from a .env file.
+func dotenvQuote(s string) string {
+	if !strings.ContainsAny(s, " \t\r\n#'\"\\$") {
+		return s
+	}
+	if !strings.ContainsAny(s, "'\r\n") {
+		return "'" + s + "'"
+	}
+	r := strings.NewReplacer("\\", `\\`, `"`, `\"`, "$", `\$`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// yamlQuote quotes s as a YAML string.  Go's double-quoted string escapes are all valid in YAML.
+func yamlQuote(s string) string {
+	return strconv.Quote(s)
+}
+
+// errWriter writes formatted text to w until the first error.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) printf(format string, args ...interface{}) {
+	if ew.err == nil {
+		_, ew.err = fmt.Fprintf(ew.w, format, args...)
+	}
+}
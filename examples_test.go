@@ -0,0 +1,155 @@
+package envcfg
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type exampleUpstream struct {
+	Host string `env:"HOST"`
+}
+
+type exampleDB struct {
+	Host     string `env:"HOST" default:"localhost"`
+	Password string `env:"PASSWORD" secret:"true" desc:"database password"`
+}
+
+type exampleConfig struct {
+	exampleDB `envPrefix:"DB_"`
+	Name      string            `env:"NAME" desc:"the app's name"`
+	Greeting  string            `env:"GREETING" default:"it's $5"`
+	Timeout   time.Duration     `env:"TIMEOUT" default:"5s"`
+	ProxyURL  string            `env:"PROXY_URL" optional:"true"`
+	Pair      []string          `env:"PAIR_A,PAIR_B" default:"a,b"`
+	Upstreams []exampleUpstream `envPrefix:"UPSTREAM_"`
+}
+
+func TestWriteDotenvExample(t *testing.T) {
+	var b bytes.Buffer
+	assert.Nil(t, WriteDotenvExample(&b, &exampleConfig{}))
+	assert.Equal(t, `# string, optional
+DB_HOST=localhost
+
+# database password
+# string, required
+DB_PASSWORD=
+
+# the app's name
+# string, required
+NAME=
+
+# string, optional
+GREETING="it's \$5"
+
+# time.Duration, optional
+TIMEOUT=5s
+
+# string, optional
+# PROXY_URL=
+
+# []string, optional
+PAIR_A=a
+PAIR_B=b
+
+# string, required
+# UPSTREAM_<N>_HOST=
+`, b.String())
+
+	// the example reads back with the defaults in place
+	path := filepath.Join(t.TempDir(), ".env.example")
+	assert.Nil(t, os.WriteFile(path, b.Bytes(), 0600))
+	vals, err := ReadDotenv(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "it's $5", vals["GREETING"])
+	assert.Equal(t, "", vals["NAME"])
+	_, ok := vals["PROXY_URL"]
+	assert.False(t, ok)
+}
+
+func TestWriteKubernetesExample(t *testing.T) {
+	var b bytes.Buffer
+	assert.Nil(t, WriteKubernetesExample(&b, &exampleConfig{}, "myapp"))
+	assert.Equal(t, `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: "myapp"
+data:
+  # string, optional
+  DB_HOST: "localhost"
+  # the app's name
+  # string, required
+  NAME: ""
+  # string, optional
+  GREETING: "it's $5"
+  # time.Duration, optional
+  TIMEOUT: "5s"
+  # string, optional
+  # PROXY_URL: ""
+  # []string, optional
+  PAIR_A: "a"
+  PAIR_B: "b"
+  # string, required
+  # UPSTREAM_<N>_HOST: ""
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: "myapp"
+type: Opaque
+stringData:
+  # database password
+  # string, required
+  DB_PASSWORD: ""
+`, b.String())
+
+	// no secrets, no Secret
+	type noSecrets struct {
+		Name string `env:"NAME"`
+	}
+	b.Reset()
+	assert.Nil(t, WriteKubernetesExample(&b, &noSecrets{}, "myapp"))
+	assert.Equal(t, "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: \"myapp\"\ndata:\n  # string, required\n  NAME: \"\"\n", b.String())
+}
+
+func TestWriteComposeExample(t *testing.T) {
+	type myConfig struct {
+		Name    string        `env:"NAME" desc:"the app's name"`
+		Timeout time.Duration `env:"TIMEOUT" default:"5s"`
+	}
+	var b bytes.Buffer
+	assert.Nil(t, WriteComposeExample(&b, &myConfig{}))
+	assert.Equal(t, `environment:
+  # the app's name
+  # string, required
+  NAME: ""
+  # time.Duration, optional
+  TIMEOUT: "5s"
+`, b.String())
+
+	// a variable shared by two fields is listed once
+	type sharedConfig struct {
+		Name  string `env:"NAME"`
+		Alias string `env:"NAME"`
+	}
+	b.Reset()
+	assert.Nil(t, WriteComposeExample(&b, &sharedConfig{}))
+	assert.Equal(t, `environment:
+  # string, required
+  NAME: ""
+`, b.String())
+
+	b.Reset()
+	assert.Nil(t, WriteComposeExample(&b, &struct{}{}))
+	assert.Equal(t, "environment: {}\n", b.String())
+
+	type badConfig struct {
+		Pair string `env:"A,B" default:"a"`
+	}
+	err := WriteComposeExample(&b, &badConfig{})
+	assert.Equal(t, "envcfg: env tag A,B has 2 names but default tag a has 1 values", err.Error())
+}
@@ -0,0 +1,146 @@
+package envcfg
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// this file holds the binding of command line flags to config struct fields, so that CLIs don't
+// have to declare every setting twice.
+
+// flagBinding remembers which variable each flag in a flag set stands for.
+type flagBinding struct {
+	fs *flag.FlagSet
+	// a map from flag names to variable names.
+	keys map[string]string
+}
+
+// BindFlags defines a flag on fs for each variable that the fields of c (a pointer to a config
+// struct) are loaded from.  The flag's name is the variable's name in lower case with dashes instead
+// of underscores, so DB_HOST becomes -db-host.  Its default comes from the field's default tag, and
+// its help text from the field's desc tag.  If any of the flags can't be defined, none of them are.
+//
+// After fs is parsed, the values of any flags that were set override every other source whenever
+// this Loader loads config, for any struct.  Fields inside numbered lists and maps of structs don't
+// get flags.
+func (e *Loader) BindFlags(fs *flag.FlagSet, c interface{}) error {
+	structType, err := configType(c)
+	if err != nil {
+		return err
+	}
+	binding := &flagBinding{fs: fs, keys: map[string]string{}}
+	// the flags are defined only once they've all been checked, so that a failed binding doesn't
+	// leave some of them on fs.
+	type flagDef struct {
+		name, usage string
+		val         *flagValue
+	}
+	var defs []flagDef
+	err = walkFields("", structType.Name(), structType, func(tf taggedField) error {
+		if tf.dynamic {
+			return nil
+		}
+		if err := tf.checkDefaults(); err != nil {
+			return err
+		}
+		// fields loaded from a single variable can have their values checked as soon as they're
+		// set, so that bad flags get the flag package's usual error message.
+		var p *parser
+		if len(tf.keys) == 1 {
+			if fp, ok, err := e.fieldParser(tf.field, tf.path, 1); err != nil {
+				return err
+			} else if ok {
+				p = &fp
+			}
+		}
+		for i, key := range tf.keys {
+			name := flagName(key)
+			bound, ok := binding.keys[name]
+			if ok && bound == key {
+				// another field uses the same variable.
+				continue
+			}
+			if ok || fs.Lookup(name) != nil {
+				return fmt.Errorf("envcfg: cannot bind flag -%s for %s: the flag is already defined", name, tf.path)
+			}
+			val := &flagValue{parser: p, isBool: len(tf.keys) == 1 && tf.field.Type.Kind() == reflect.Bool}
+			if tf.hasDefault {
+				val.s = tf.defaults[i]
+			}
+			defs = append(defs, flagDef{name: name, usage: flagUsage(tf.field.Tag.Get(descTag), key), val: val})
+			binding.keys[name] = key
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, def := range defs {
+		fs.Var(def.val, def.name, def.usage)
+	}
+	e.flagMu.Lock()
+	defer e.flagMu.Unlock()
+	e.flagBindings = append(e.flagBindings, binding)
+	return nil
+}
+
+// flagSources returns a Source for each of the Loader's flag bindings, holding the flags that were
+// set.
+func (e *Loader) flagSources() []Source {
+	e.flagMu.Lock()
+	defer e.flagMu.Unlock()
+	var sources []Source
+	for _, binding := range e.flagBindings {
+		vals := MapSource{}
+		binding.fs.Visit(func(f *flag.Flag) {
+			if key, ok := binding.keys[f.Name]; ok {
+				vals[key] = f.Value.String()
+			}
+		})
+		sources = append(sources, vals)
+	}
+	return sources
+}
+
+// flagName returns the name of the flag for the named variable.
+func flagName(key string) string {
+	return strings.ReplaceAll(strings.ToLower(key), "_", "-")
+}
+
+// flagUsage returns the help text for the flag for the named variable.
+func flagUsage(desc, key string) string {
+	if desc == "" {
+		return "sets $" + key
+	}
+	return desc + " ($" + key + ")"
+}
+
+// flagValue is a flag.Value that holds a variable's value as a string, for the Loader to parse
+// later.
+type flagValue struct {
+	s string
+	// if set, Set checks that its argument can be parsed.
+	parser *parser
+	isBool bool
+}
+
+func (v *flagValue) String() string {
+	return v.s
+}
+
+func (v *flagValue) Set(s string) error {
+	if v.parser != nil {
+		if _, err := v.parser.f(s); err != nil {
+			return err
+		}
+	}
+	v.s = s
+	return nil
+}
+
+// IsBoolFlag lets boolean flags be given without a value, like -debug.
+func (v *flagValue) IsBoolFlag() bool {
+	return v.isBool
+}
@@ -0,0 +1,111 @@
+package envcfg
+
+import (
+	"bytes"
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBindFlags(t *testing.T) {
+	type DBConfig struct {
+		Host string `env:"HOST" default:"localhost" desc:"database host"`
+		Port int    `env:"PORT" default:"5432"`
+	}
+	type myConfig struct {
+		Name    string        `env:"NAME" desc:"the app's name"`
+		Debug   bool          `env:"DEBUG" default:"false"`
+		Timeout time.Duration `env:"TIMEOUT" default:"5s"`
+		Alias   string        `env:"NAME"`
+		DB      DBConfig      `envPrefix:"DB_"`
+	}
+
+	ec, err := New()
+	assert.Nil(t, err)
+	fs := flag.NewFlagSet("myapp", flag.ContinueOnError)
+	var conf myConfig
+	assert.Nil(t, ec.BindFlags(fs, &conf))
+
+	var help bytes.Buffer
+	fs.SetOutput(&help)
+	fs.PrintDefaults()
+	assert.Equal(t, ""+
+		"  -db-host value\n"+
+		"    \tdatabase host ($DB_HOST) (default localhost)\n"+
+		"  -db-port value\n"+
+		"    \tsets $DB_PORT (default 5432)\n"+
+		"  -debug\n"+
+		"    \tsets $DEBUG (default false)\n"+
+		"  -name value\n"+
+		"    \tthe app's name ($NAME)\n"+
+		"  -timeout value\n"+
+		"    \tsets $TIMEOUT (default 5s)\n",
+		help.String(),
+	)
+
+	assert.Nil(t, fs.Parse([]string{"-debug", "-db-host", "db.example.com", "-timeout=1m"}))
+	err = ec.LoadFromMap(map[string]string{
+		"NAME":    "myapp",
+		"DB_HOST": "ignored.example.com",
+		"DB_PORT": "6543",
+		"TIMEOUT": "10s",
+	}, &conf)
+	assert.Nil(t, err)
+	assert.Equal(t, myConfig{
+		Name:    "myapp",
+		Debug:   true,
+		Timeout: time.Minute,
+		Alias:   "myapp",
+		DB:      DBConfig{Host: "db.example.com", Port: 6543},
+	}, conf)
+
+	// flags that aren't set don't override defaults or other sources
+	conf = myConfig{}
+	err = ec.LoadFromMap(map[string]string{"NAME": "myapp"}, &conf)
+	assert.Nil(t, err)
+	assert.Equal(t, DBConfig{Host: "db.example.com", Port: 5432}, conf.DB)
+
+	// bad values are caught when the flags are parsed
+	assert.Equal(t,
+		`invalid value "soon" for flag -timeout: time: invalid duration "soon"`,
+		fs.Parse([]string{"-timeout", "soon"}).Error(),
+	)
+
+	// other loaders aren't affected
+	conf = myConfig{}
+	err = LoadFromMap(map[string]string{"NAME": "myapp"}, &conf)
+	assert.Nil(t, err)
+	assert.Equal(t, "localhost", conf.DB.Host)
+}
+
+func TestBindFlagsErrors(t *testing.T) {
+	type myConfig struct {
+		Name string `env:"NAME"`
+	}
+	fs := flag.NewFlagSet("myapp", flag.ContinueOnError)
+	fs.String("name", "", "already here")
+	err := BindFlags(fs, &myConfig{})
+	assert.Equal(t, "envcfg: cannot bind flag -name for myConfig.Name: the flag is already defined", err.Error())
+
+	// a failed binding leaves no flags behind
+	type twoConfig struct {
+		Host string `env:"HOST"`
+		Name string `env:"NAME"`
+	}
+	ec, _ := New()
+	err = ec.BindFlags(fs, &twoConfig{})
+	assert.Equal(t, "envcfg: cannot bind flag -name for twoConfig.Name: the flag is already defined", err.Error())
+	assert.Nil(t, fs.Lookup("host"))
+	assert.Empty(t, ec.flagBindings)
+
+	err = BindFlags(fs, myConfig{})
+	assert.Equal(t, "envcfg: {} is not a pointer", err.Error())
+
+	type badConfig struct {
+		Pair string `env:"A,B" default:"a"`
+	}
+	err = BindFlags(flag.NewFlagSet("myapp", flag.ContinueOnError), &badConfig{})
+	assert.Equal(t, "envcfg: env tag A,B has 2 names but default tag a has 1 values", err.Error())
+}
@@ -0,0 +1,105 @@
+package envcfg
+
+import (
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The default formatters are the inverses of the default parsers, for turning values back into
+// strings that the default parsers will read.  Like the parsers, these are exported so users can
+// start with an envcfg.Empty() and pick and choose which of them to register.  There's no
+// formatter for *template.Template, since a parsed template can't be turned back into its source.
+var DefaultFormatters = []interface{}{
+	FormatBool,
+	FormatString,
+	FormatInt,
+	FormatFloat32,
+	FormatFloat64,
+	FormatInt8,
+	FormatInt16,
+	FormatInt32,
+	FormatInt64,
+	FormatUint,
+	FormatUint8,
+	FormatUint16,
+	FormatUint32,
+	FormatUint64,
+	FormatDuration,
+	FormatTime,
+	FormatURL,
+	FormatMAC,
+	FormatIP,
+	FormatEmailAddress,
+	FormatEmailAddressList,
+	FormatBytes,
+}
+
+func FormatBool(b bool) (string, error)       { return strconv.FormatBool(b), nil }
+func FormatString(s string) (string, error)   { return s, nil }
+func FormatInt(i int) (string, error)         { return strconv.Itoa(i), nil }
+func FormatInt8(i int8) (string, error)       { return strconv.FormatInt(int64(i), 10), nil }
+func FormatInt16(i int16) (string, error)     { return strconv.FormatInt(int64(i), 10), nil }
+func FormatInt32(i int32) (string, error)     { return strconv.FormatInt(int64(i), 10), nil }
+func FormatInt64(i int64) (string, error)     { return strconv.FormatInt(i, 10), nil }
+func FormatUint(i uint) (string, error)       { return strconv.FormatUint(uint64(i), 10), nil }
+func FormatUint8(i uint8) (string, error)     { return strconv.FormatUint(uint64(i), 10), nil }
+func FormatUint16(i uint16) (string, error)   { return strconv.FormatUint(uint64(i), 10), nil }
+func FormatUint32(i uint32) (string, error)   { return strconv.FormatUint(uint64(i), 10), nil }
+func FormatUint64(i uint64) (string, error)   { return strconv.FormatUint(i, 10), nil }
+func FormatFloat64(f float64) (string, error) { return strconv.FormatFloat(f, 'g', -1, 64), nil }
+func FormatTime(t time.Time) (string, error)  { return t.Format(time.RFC3339Nano), nil }
+func FormatURL(u *url.URL) (string, error)    { return u.String(), nil }
+func FormatBytes(b []byte) (string, error)    { return string(b), nil }
+
+func FormatFloat32(f float32) (string, error) {
+	return strconv.FormatFloat(float64(f), 'g', -1, 32), nil
+}
+
+// FormatDuration formats d like time.Duration's String method, but without trailing zero units, so
+// 2h30m0s becomes 2h30m.
+func FormatDuration(d time.Duration) (string, error) {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s, nil
+}
+
+// The parsers for IPs, MAC addresses, and address lists reject empty strings, so these formatters
+// return errors for empty values rather than strings that can't be loaded.  Marshal leaves such
+// fields out instead of calling them.
+
+func FormatIP(ip net.IP) (string, error) {
+	if len(ip) == 0 {
+		return "", fmt.Errorf("empty IP address")
+	}
+	return ip.String(), nil
+}
+
+func FormatMAC(a net.HardwareAddr) (string, error) {
+	if len(a) == 0 {
+		return "", fmt.Errorf("empty MAC address")
+	}
+	return a.String(), nil
+}
+
+func FormatEmailAddress(a *mail.Address) (string, error) { return a.String(), nil }
+
+func FormatEmailAddressList(list []*mail.Address) (string, error) {
+	if len(list) == 0 {
+		return "", fmt.Errorf("empty address list")
+	}
+	out := make([]string, len(list))
+	for i, a := range list {
+		out[i] = a.String()
+	}
+	return strings.Join(out, ", "), nil
+}
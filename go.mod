@@ -4,8 +4,6 @@ go 1.16
 
 require (
 	github.com/davecgh/go-spew v1.1.0 // indirect
-	github.com/hashicorp/errwrap v0.0.0-20141028054710-7554cd9344ce // indirect
-	github.com/hashicorp/go-multierror v0.0.0-20171204182908-b7773ae21874
 	github.com/lib/pq v0.0.0-20180201184707-88edab080323
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/stretchr/testify v1.2.1
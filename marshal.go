@@ -0,0 +1,452 @@
+package envcfg
+
+import (
+	"encoding"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"net/mail"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// this file holds the reverse of loading:  turning a config struct back into variables, with
+// formatter funcs that are the inverses of the parser funcs.
+
+// Our internal formatter func takes a value and returns one string for each variable it's loaded
+// from.  Funcs of this type wrap the default formatters and user-provided formatters that take
+// arbitrary types.
+type formatter struct {
+	f       func(reflect.Value) ([]string, error)
+	numArgs int
+}
+
+// omittedWhenEmpty holds the slice types whose default parsers reject an empty string, so that
+// Marshal leaves them out when they're empty, like nil pointers.
+var omittedWhenEmpty = map[reflect.Type]bool{
+	reflect.TypeOf(net.IP{}):           true,
+	reflect.TypeOf(net.HardwareAddr{}): true,
+	reflect.TypeOf([]*mail.Address{}):  true,
+}
+
+var (
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	binaryMarshalerType = reflect.TypeOf((*encoding.BinaryMarshaler)(nil)).Elem()
+	jsonMarshalerType   = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// RegisterFormatter takes a func (<anytype>) (string, error) and registers it on the Loader as the
+// formatter for <anytype>, the inverse of a parser registered with RegisterParser.  For fields
+// loaded from several variables, the func can return several strings before the error, one for
+// each variable.
+func (e *Loader) RegisterFormatter(f interface{}) error {
+	t := reflect.TypeOf(f)
+	if t.Kind() != reflect.Func {
+		return fmt.Errorf("envcfg: %v is not a func", f)
+	}
+
+	fname := funcName(f)
+	if t.NumIn() != 1 {
+		return fmt.Errorf("envcfg: formatter should accept 1 argument. %s accepts %d arguments", fname, t.NumIn())
+	}
+	// it should return at least one string, and an error
+	if t.NumOut() < 2 {
+		return fmt.Errorf(
+			"envcfg: formatter should return at least 2 values. %s returns %d values",
+			fname, t.NumOut())
+	}
+	for n := 0; n < t.NumOut()-1; n++ {
+		if t.Out(n) != stringType {
+			return fmt.Errorf(
+				"envcfg: formatter should return strings before the error. %s returns a %v",
+				fname, t.Out(n))
+		}
+	}
+	errorInterface := reflect.TypeOf((*error)(nil)).Elem()
+	if !t.Out(t.NumOut() - 1).Implements(errorInterface) {
+		return fmt.Errorf(
+			"envcfg: formatter's last return value should be error. %s's last return value is %v",
+			fname, t.Out(t.NumOut()-1))
+	}
+	key := parserKey{
+		typ:     t.In(0),
+		numArgs: t.NumOut() - 1,
+	}
+	if _, alreadyRegistered := e.formatters[key]; alreadyRegistered {
+		return fmt.Errorf(
+			"envcfg: a formatter has already been registered for the %v type with %d outputs.  cannot also register %s",
+			t.In(0),
+			key.numArgs,
+			fname,
+		)
+	}
+
+	callable := reflect.ValueOf(f)
+	wrapped := func(v reflect.Value) (ss []string, err error) {
+		defer func() {
+			p := recover()
+			if p != nil {
+				// we panicked running the inner formatter function.
+				err = fmt.Errorf("%s panicked: %s", fname, p)
+			}
+		}()
+		returnvals := callable.Call([]reflect.Value{v})
+		last := returnvals[len(returnvals)-1]
+		if !last.IsNil() {
+			return nil, last.Interface().(error)
+		}
+		for _, s := range returnvals[:len(returnvals)-1] {
+			ss = append(ss, s.String())
+		}
+		return ss, nil
+	}
+	e.formatters[key] = formatter{f: wrapped, numArgs: key.numArgs}
+	return nil
+}
+
+// MustRegisterFormatter attempts to register the provided formatter func and panics if it gets an
+// error.
+func (e *Loader) MustRegisterFormatter(f interface{}) {
+	if err := e.RegisterFormatter(f); err != nil {
+		panic(err)
+	}
+}
+
+// findFormatter returns the formatter for values of type typ that are loaded from numArgs
+// variables, trying each of the Loader's strategies in order, so that values are formatted the way
+// they'd be parsed.
+func (e *Loader) findFormatter(typ reflect.Type, numArgs int) (formatter, bool) {
+	for _, strategy := range e.strategies {
+		if strategy != ParserFuncs && numArgs != 1 {
+			continue
+		}
+		var f formatter
+		var ok bool
+		switch strategy {
+		case ParserFuncs:
+			f, ok = e.formatters[parserKey{typ: typ, numArgs: numArgs}]
+		case TextUnmarshalers:
+			f, ok = interfaceFormatter(typ, textMarshalerType, func(v interface{}) (string, error) {
+				b, err := v.(encoding.TextMarshaler).MarshalText()
+				return string(b), err
+			})
+		case FlagValues:
+			f, ok = interfaceFormatter(typ, flagValueType, func(v interface{}) (string, error) {
+				return v.(flag.Value).String(), nil
+			})
+		case JSONUnmarshalers:
+			f, ok = interfaceFormatter(typ, jsonMarshalerType, func(v interface{}) (string, error) {
+				b, err := v.(json.Marshaler).MarshalJSON()
+				return string(b), err
+			})
+		case BinaryUnmarshalers:
+			f, ok = interfaceFormatter(typ, binaryMarshalerType, func(v interface{}) (string, error) {
+				b, err := v.(encoding.BinaryMarshaler).MarshalBinary()
+				return string(b), err
+			})
+		}
+		if ok {
+			return f, true
+		}
+	}
+	return formatter{}, false
+}
+
+// interfaceFormatter builds a formatter for typ out of an interface that typ (or *typ) implements.
+// The marshal func is called with a value that implements iface.
+func interfaceFormatter(
+	typ reflect.Type,
+	iface reflect.Type,
+	marshal func(v interface{}) (string, error),
+) (formatter, bool) {
+	switch {
+	case typ.Implements(iface):
+		return formatter{
+			f: func(v reflect.Value) ([]string, error) {
+				s, err := marshal(v.Interface())
+				return []string{s}, err
+			},
+			numArgs: 1,
+		}, true
+	case reflect.PtrTo(typ).Implements(iface):
+		// methods with pointer receivers on a non-pointer field type.  Copy the value somewhere
+		// addressable.
+		return formatter{
+			f: func(v reflect.Value) ([]string, error) {
+				p := reflect.New(typ)
+				p.Elem().Set(v)
+				s, err := marshal(p.Interface())
+				return []string{s}, err
+			},
+			numArgs: 1,
+		}, true
+	}
+	return formatter{}, false
+}
+
+// fieldFormatter returns the formatter for the given struct field loaded from numArgs variables,
+// building one for slices and maps like fieldParser does.  The error return is only for invalid
+// struct tags.
+func (e *Loader) fieldFormatter(field reflect.StructField, fieldPath string, numArgs int) (formatter, bool, error) {
+	f, ok := e.findFormatter(field.Type, numArgs)
+	if ok || numArgs != 1 {
+		return f, ok, nil
+	}
+
+	if field.Type.Kind() == reflect.Slice {
+		elemFormatter, ok := e.findFormatter(field.Type.Elem(), 1)
+		if !ok {
+			return formatter{}, false, nil
+		}
+		sep, err := separator(field, fieldPath, sepTag, defaultSep)
+		if err != nil {
+			return formatter{}, false, err
+		}
+		return sliceFormatter(elemFormatter, sep), true, nil
+	}
+
+	if field.Type.Kind() == reflect.Map {
+		keyFormatter, ok := e.findFormatter(field.Type.Key(), 1)
+		if !ok {
+			return formatter{}, false, nil
+		}
+		valFormatter, ok := e.findFormatter(field.Type.Elem(), 1)
+		if !ok {
+			return formatter{}, false, nil
+		}
+		sep, err := separator(field, fieldPath, sepTag, defaultSep)
+		if err != nil {
+			return formatter{}, false, err
+		}
+		kvSep, err := separator(field, fieldPath, kvSepTag, defaultKVSep)
+		if err != nil {
+			return formatter{}, false, err
+		}
+		if sep == kvSep {
+			return formatter{}, false, &InvalidTagError{
+				Field: fieldPath,
+				Tag:   kvSepTag,
+				Value: string(kvSep),
+				Want:  "different from the " + sepTag + " separator",
+			}
+		}
+		return mapFormatter(keyFormatter, valFormatter, sep, kvSep), true, nil
+	}
+	return formatter{}, false, nil
+}
+
+// sliceFormatter returns a formatter for a slice that formats each element with elemFormatter and
+// joins them with sep, escaping any separators in the elements.
+func sliceFormatter(elemFormatter formatter, sep rune) formatter {
+	return formatter{
+		f: func(v reflect.Value) ([]string, error) {
+			elems := make([]string, v.Len())
+			for i := range elems {
+				ss, err := elemFormatter.f(v.Index(i))
+				if err != nil {
+					return nil, fmt.Errorf("element %d: %v", i, err)
+				}
+				if len(elems) == 1 && ss[0] == "" {
+					return nil, fmt.Errorf("a single empty element would load as an empty slice")
+				}
+				if i < len(elems)-1 && strings.HasSuffix(ss[0], `\`) {
+					return nil, fmt.Errorf(
+						"element %d (%q) ends with a backslash, which would escape the %q separator after it",
+						i, ss[0], sep)
+				}
+				elems[i] = escapeElem(ss[0], sep)
+			}
+			return []string{strings.Join(elems, string(sep))}, nil
+		},
+		numArgs: 1,
+	}
+}
+
+// mapFormatter returns a formatter for a map that formats each key and value with keyFormatter and
+// valFormatter, joins them with kvSep, and joins the pairs with sep, sorted.  Separators in the
+// keys and values are escaped.
+func mapFormatter(keyFormatter, valFormatter formatter, sep, kvSep rune) formatter {
+	return formatter{
+		f: func(v reflect.Value) ([]string, error) {
+			type pair struct {
+				key, val, s string
+			}
+			var pairs []pair
+			iter := v.MapRange()
+			for iter.Next() {
+				k, err := keyFormatter.f(iter.Key())
+				if err != nil {
+					return nil, fmt.Errorf("key %v: %v", iter.Key(), err)
+				}
+				val, err := valFormatter.f(iter.Value())
+				if err != nil {
+					return nil, fmt.Errorf("value for key %q: %v", k[0], err)
+				}
+				if strings.HasSuffix(k[0], `\`) {
+					return nil, fmt.Errorf("key %q ends with a backslash, which would escape the %q separator after it",
+						k[0], kvSep)
+				}
+				key := escapeElem(escapeElem(k[0], kvSep), sep)
+				s := key + string(kvSep) + escapeElem(escapeElem(val[0], kvSep), sep)
+				pairs = append(pairs, pair{key: k[0], val: val[0], s: s})
+			}
+			sort.Slice(pairs, func(a, b int) bool { return pairs[a].s < pairs[b].s })
+
+			out := make([]string, len(pairs))
+			for i, p := range pairs {
+				if i < len(pairs)-1 && strings.HasSuffix(p.val, `\`) {
+					return nil, fmt.Errorf(
+						"value for key %q (%q) ends with a backslash, which would escape the %q separator after it",
+						p.key, p.val, sep)
+				}
+				out[i] = p.s
+			}
+			return []string{strings.Join(out, string(sep))}, nil
+		},
+		numArgs: 1,
+	}
+}
+
+// Marshal returns the variables that would load the config struct that c points to, the inverse of
+// LoadFromMap:  loading them with LoadFromMap gives back an equal struct, except that nil slices
+// and maps come back empty.  Values that wouldn't load back the same are an error, like a slice
+// with a single empty element, or a slice element or map key that ends in a backslash, since the
+// backslash would escape the separator after it.  Fields are formatted with the Loader's
+// formatters, or the types' own MarshalText, String (for flag.Value types), MarshalJSON, or
+// MarshalBinary methods, in the same order that the Loader tries parsers.  Nil pointers are left
+// out, and so are empty net.IP, net.HardwareAddr, and []*mail.Address values, since their parsers
+// reject empty strings.
+func (e *Loader) Marshal(c interface{}) (map[string]string, error) {
+	structType, err := configType(c)
+	if err != nil {
+		return nil, err
+	}
+	m := &marshaler{
+		loader:     e,
+		vals:       map[string]string{},
+		setBy:      map[string]string{},
+		marshaling: map[reflect.Type]bool{},
+	}
+	if err := m.marshalStruct("", structType.Name(), structType, reflect.ValueOf(c).Elem()); err != nil {
+		return nil, err
+	}
+	return m.vals, nil
+}
+
+// marshaler holds the state of a single call to Marshal.
+type marshaler struct {
+	loader *Loader
+	vals   map[string]string
+	// the field that set each variable, for reporting conflicts.
+	setBy map[string]string
+	// the struct types currently being marshaled, like loadState.loading.
+	marshaling map[reflect.Type]bool
+}
+
+// marshalStruct adds the variables for the fields of structVal, following the same rules as
+// loadStructFields.
+func (m *marshaler) marshalStruct(prefix, path string, structType reflect.Type, structVal reflect.Value) error {
+	m.marshaling[structType] = true
+	defer delete(m.marshaling, structType)
+
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		fieldPath := joinPath(path, field.Name)
+		fieldVal := structVal.Field(i)
+		tagVal, ok := field.Tag.Lookup(cfgTag)
+		fieldPrefix := prefix + field.Tag.Get(prefixTag)
+
+		var err error
+		switch {
+		case isNestedStruct(field, ok):
+			if field.Type.Kind() != reflect.Ptr {
+				err = m.marshalStruct(fieldPrefix, fieldPath, field.Type, fieldVal)
+			} else {
+				err = m.marshalStructPtr(fieldPrefix, fieldPath, field.Type.Elem(), fieldVal)
+			}
+		case isStructSlice(field, ok):
+			for j := 0; j < fieldVal.Len() && err == nil; j++ {
+				elemPrefix := fieldPrefix + strconv.Itoa(j) + "_"
+				elemPath := fmt.Sprintf("%s[%d]", fieldPath, j)
+				err = m.marshalStruct(elemPrefix, elemPath, field.Type.Elem(), fieldVal.Index(j))
+			}
+		case isStructMap(field, ok):
+			keys := fieldVal.MapKeys()
+			sort.Slice(keys, func(a, b int) bool { return keys[a].String() < keys[b].String() })
+			for _, key := range keys {
+				name := key.String()
+				elemPath := fmt.Sprintf("%s[%s]", fieldPath, name)
+				err = m.marshalStruct(fieldPrefix+name+"_", elemPath, field.Type.Elem(), fieldVal.MapIndex(key))
+				if err != nil {
+					break
+				}
+			}
+		case ok:
+			err = m.marshalField(prefix, tagVal, field, fieldPath, fieldVal)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// marshalStructPtr adds the variables for a pointer-to-struct field, if it isn't nil.  Like
+// loadStructPtr, it refuses a struct type that contains a pointer to itself, which couldn't be
+// loaded.  Slices and maps of the struct's own type are fine, since they hold finite data.
+func (m *marshaler) marshalStructPtr(prefix, path string, structType reflect.Type, fieldVal reflect.Value) error {
+	if !hasEnvTags(structType, map[reflect.Type]bool{}) {
+		return nil
+	}
+	if m.marshaling[structType] {
+		return fmt.Errorf("envcfg: cannot marshal field %s: %v refers to itself", path, structType)
+	}
+	if fieldVal.IsNil() {
+		return nil
+	}
+	return m.marshalStruct(prefix, path, structType, fieldVal.Elem())
+}
+
+// marshalField adds the variables for a field with an env tag.
+func (m *marshaler) marshalField(
+	prefix string,
+	tagVal string,
+	field reflect.StructField,
+	fieldPath string,
+	fieldVal reflect.Value,
+) error {
+	if (fieldVal.Kind() == reflect.Ptr || fieldVal.Kind() == reflect.Interface) && fieldVal.IsNil() {
+		return nil
+	}
+	if omittedWhenEmpty[field.Type] && fieldVal.Len() == 0 {
+		return nil
+	}
+	if !fieldVal.CanInterface() {
+		return fmt.Errorf("envcfg: cannot marshal unexported field %s", fieldPath)
+	}
+
+	envKeys := strings.Split(tagVal, tagSep)
+	f, ok, err := m.loader.fieldFormatter(field, fieldPath, len(envKeys))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("envcfg: no formatter function found for type %v (field %s)", field.Type, fieldPath)
+	}
+	ss, err := f.f(fieldVal)
+	if err != nil {
+		return fmt.Errorf("envcfg: cannot format %s: %v", fieldPath, err)
+	}
+	for i, key := range envKeys {
+		key = prefix + key
+		if old, set := m.vals[key]; set && old != ss[i] {
+			return fmt.Errorf("envcfg: %s and %s both set %s, to %q and %q", m.setBy[key], fieldPath, key, old, ss[i])
+		}
+		m.vals[key] = ss[i]
+		m.setBy[key] = fieldPath
+	}
+	return nil
+}
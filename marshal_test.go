@@ -0,0 +1,336 @@
+package envcfg
+
+import (
+	"fmt"
+	"html/template"
+	"math/big"
+	"net"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func (l logLevel) MarshalText() ([]byte, error) {
+	switch l {
+	case 0:
+		return []byte("debug"), nil
+	case 1:
+		return []byte("info"), nil
+	}
+	return nil, fmt.Errorf("unknown log level %d", int(l))
+}
+
+type marshalDB struct {
+	Host string `env:"HOST"`
+	Port int    `env:"PORT" default:"5432"`
+}
+
+type marshalUpstream struct {
+	URL     *url.URL      `env:"URL"`
+	Timeout time.Duration `env:"TIMEOUT" optional:"true"`
+}
+
+type marshalConfig struct {
+	Name     string                     `env:"NAME"`
+	Debug    bool                       `env:"DEBUG"`
+	Ratio    float64                    `env:"RATIO"`
+	Timeout  time.Duration              `env:"TIMEOUT"`
+	Started  time.Time                  `env:"STARTED"`
+	Home     *url.URL                   `env:"HOME"`
+	IP       net.IP                     `env:"IP"`
+	Admins   []*mail.Address            `env:"ADMINS"`
+	Tags     []string                   `env:"TAGS"`
+	Ports    []uint16                   `env:"PORTS" sep:";"`
+	Labels   map[string]string          `env:"LABELS"`
+	Level    logLevel                   `env:"LEVEL"`
+	Big      *big.Int                   `env:"BIG"`
+	Backup   *url.URL                   `env:"BACKUP" optional:"true"`
+	DB       marshalDB                  `envPrefix:"DB_"`
+	Replicas []marshalDB                `envPrefix:"REPLICA_"`
+	Upstream map[string]marshalUpstream `envPrefix:"UPSTREAM_"`
+}
+
+func TestMarshal(t *testing.T) {
+	home, _ := url.Parse("https://example.com/home?q=1")
+	api, _ := url.Parse("https://api.example.com")
+	big, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	conf := marshalConfig{
+		Name:    "myapp",
+		Debug:   true,
+		Ratio:   0.25,
+		Timeout: 2*time.Hour + 30*time.Minute,
+		Started: time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC),
+		Home:    home,
+		IP:      net.ParseIP("10.0.0.1"),
+		Admins: []*mail.Address{
+			{Name: "Alice", Address: "alice@example.com"},
+			{Address: "bob@example.com"},
+		},
+		Tags:     []string{"a,b", "c"},
+		Ports:    []uint16{80, 443},
+		Labels:   map[string]string{"team": "core", "k=v": "x,y"},
+		Level:    1,
+		Big:      big,
+		DB:       marshalDB{Host: "db.local", Port: 5432},
+		Replicas: []marshalDB{{Host: "r0", Port: 1}, {Host: "r1", Port: 2}},
+		Upstream: map[string]marshalUpstream{"API": {URL: api, Timeout: time.Second}},
+	}
+
+	vals, err := Marshal(&conf)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{
+		"NAME":                 "myapp",
+		"DEBUG":                "true",
+		"RATIO":                "0.25",
+		"TIMEOUT":              "2h30m",
+		"STARTED":              "2020-01-02T03:04:05.000000006Z",
+		"HOME":                 "https://example.com/home?q=1",
+		"IP":                   "10.0.0.1",
+		"ADMINS":               `"Alice" <alice@example.com>, <bob@example.com>`,
+		"TAGS":                 `a\,b,c`,
+		"PORTS":                "80;443",
+		"LABELS":               `k\=v=x\,y,team=core`,
+		"LEVEL":                "info",
+		"BIG":                  "123456789012345678901234567890",
+		"DB_HOST":              "db.local",
+		"DB_PORT":              "5432",
+		"REPLICA_0_HOST":       "r0",
+		"REPLICA_0_PORT":       "1",
+		"REPLICA_1_HOST":       "r1",
+		"REPLICA_1_PORT":       "2",
+		"UPSTREAM_API_URL":     "https://api.example.com",
+		"UPSTREAM_API_TIMEOUT": "1s",
+	}, vals)
+
+	var loaded marshalConfig
+	assert.Nil(t, LoadFromMap(vals, &loaded))
+	assert.Equal(t, conf, loaded)
+}
+
+func TestMarshalEmptyValues(t *testing.T) {
+	type myConfig struct {
+		IP     net.IP           `env:"IP" optional:"true"`
+		MAC    net.HardwareAddr `env:"MAC" optional:"true"`
+		Admins []*mail.Address  `env:"ADMINS" optional:"true"`
+		Tags   []string         `env:"TAGS"`
+	}
+	// the parsers reject empty IPs, MAC addresses, and address lists, so they're left out.
+	var conf myConfig
+	vals, err := Marshal(&conf)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"TAGS": ""}, vals)
+
+	var loaded myConfig
+	assert.Nil(t, LoadFromMap(vals, &loaded))
+	assert.Equal(t, myConfig{Tags: []string{}}, loaded)
+
+	_, err = FormatIP(nil)
+	assert.Equal(t, "empty IP address", err.Error())
+	_, err = FormatMAC(net.HardwareAddr{})
+	assert.Equal(t, "empty MAC address", err.Error())
+}
+
+func TestMarshalEscaping(t *testing.T) {
+	type myConfig struct {
+		Paths  []string          `env:"PATHS"`
+		Labels map[string]string `env:"LABELS"`
+	}
+
+	t.Run("round trips", func(t *testing.T) {
+		conf := myConfig{
+			Paths:  []string{`a\b`, `c\,d`, "", `e\`},
+			Labels: map[string]string{`k\=`: `v\=`, "a,b": `\,`, "z": `x\`},
+		}
+		vals, err := Marshal(&conf)
+		assert.Nil(t, err)
+		var loaded myConfig
+		assert.Nil(t, LoadFromMap(vals, &loaded))
+		assert.Equal(t, conf, loaded)
+	})
+
+	tt := []struct {
+		desc   string
+		conf   myConfig
+		errMsg string
+	}{
+		{
+			desc:   "slice element ending in a backslash",
+			conf:   myConfig{Paths: []string{`C:\`, `D:\`}},
+			errMsg: `envcfg: cannot format myConfig.Paths: element 0 ("C:\\") ends with a backslash, which would escape the ',' separator after it`,
+		},
+		{
+			desc:   "single empty element",
+			conf:   myConfig{Paths: []string{""}},
+			errMsg: "envcfg: cannot format myConfig.Paths: a single empty element would load as an empty slice",
+		},
+		{
+			desc:   "map key ending in a backslash",
+			conf:   myConfig{Labels: map[string]string{`k\`: "v"}},
+			errMsg: `envcfg: cannot format myConfig.Labels: key "k\\" ends with a backslash, which would escape the '=' separator after it`,
+		},
+		{
+			desc:   "map value ending in a backslash",
+			conf:   myConfig{Labels: map[string]string{"a": `x\`, "b": "y"}},
+			errMsg: `envcfg: cannot format myConfig.Labels: value for key "a" ("x\\") ends with a backslash, which would escape the ',' separator after it`,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.desc, func(t *testing.T) {
+			_, err := Marshal(&tc.conf)
+			assert.Equal(t, tc.errMsg, err.Error())
+		})
+	}
+}
+
+type treeNode struct {
+	Name     string     `env:"NAME"`
+	Children []treeNode `envPrefix:"CHILD_"`
+}
+
+func TestMarshalRecursiveTypes(t *testing.T) {
+	conf := treeNode{Name: "root", Children: []treeNode{
+		{Name: "a", Children: []treeNode{{Name: "a1"}}},
+		{Name: "b"},
+	}}
+	vals, err := Marshal(&conf)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{
+		"NAME":                 "root",
+		"CHILD_0_NAME":         "a",
+		"CHILD_0_CHILD_0_NAME": "a1",
+		"CHILD_1_NAME":         "b",
+	}, vals)
+	var loaded treeNode
+	assert.Nil(t, LoadFromMap(vals, &loaded))
+	assert.Equal(t, conf, loaded)
+
+	type Node struct {
+		Name string `env:"NAME"`
+		Next *Node  `envPrefix:"NEXT_"`
+	}
+	_, err = Marshal(&Node{Name: "a", Next: &Node{Name: "b"}})
+	assert.Equal(t, "envcfg: cannot marshal field Node.Next: envcfg.Node refers to itself", err.Error())
+}
+
+func TestFormatDuration(t *testing.T) {
+	tt := []struct {
+		d        time.Duration
+		expected string
+	}{
+		{0, "0s"},
+		{1500 * time.Millisecond, "1.5s"},
+		{time.Minute, "1m"},
+		{time.Hour, "1h"},
+		{2*time.Hour + 30*time.Minute, "2h30m"},
+		{time.Hour + time.Second, "1h0m1s"},
+		{-90 * time.Second, "-1m30s"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.expected, func(t *testing.T) {
+			s, err := FormatDuration(tc.d)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expected, s)
+			d, err := ParseDuration(s)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.d, d)
+		})
+	}
+}
+
+type point struct {
+	x, y int
+}
+
+func TestRegisterFormatter(t *testing.T) {
+	type myConfig struct {
+		Origin point `env:"X,Y"`
+	}
+
+	t.Run("multiple variables", func(t *testing.T) {
+		ec, _ := New()
+		ec.MustRegisterParser(func(x, y string) (point, error) {
+			px, err := strconv.Atoi(x)
+			if err != nil {
+				return point{}, err
+			}
+			py, err := strconv.Atoi(y)
+			return point{px, py}, err
+		})
+		ec.MustRegisterFormatter(func(p point) (string, string, error) {
+			return strconv.Itoa(p.x), strconv.Itoa(p.y), nil
+		})
+		conf := myConfig{Origin: point{3, -4}}
+		vals, err := ec.Marshal(&conf)
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]string{"X": "3", "Y": "-4"}, vals)
+
+		var loaded myConfig
+		assert.Nil(t, ec.LoadFromMap(vals, &loaded))
+		assert.Equal(t, conf, loaded)
+	})
+
+	t.Run("no formatter", func(t *testing.T) {
+		ec, _ := New()
+		_, err := ec.Marshal(&myConfig{})
+		assert.Equal(t, "envcfg: no formatter function found for type envcfg.point (field myConfig.Origin)", err.Error())
+	})
+
+	t.Run("formatter error", func(t *testing.T) {
+		type levelConfig struct {
+			Level logLevel `env:"LEVEL"`
+		}
+		_, err := Marshal(&levelConfig{Level: 7})
+		assert.Equal(t, "envcfg: cannot format levelConfig.Level: unknown log level 7", err.Error())
+	})
+
+	t.Run("registered formatter wins", func(t *testing.T) {
+		type levelConfig struct {
+			Level logLevel `env:"LEVEL"`
+		}
+		ec, _ := New()
+		ec.MustRegisterFormatter(func(l logLevel) (string, error) { return "level" + strconv.Itoa(int(l)), nil })
+		vals, err := ec.Marshal(&levelConfig{Level: 1})
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]string{"LEVEL": "level1"}, vals)
+	})
+
+	t.Run("invalid formatters", func(t *testing.T) {
+		ec := Empty()
+		assert.Regexp(t, "formatter should accept 1 argument", ec.RegisterFormatter(func(a, b int) (string, error) { return "", nil }).Error())
+		assert.Regexp(t, "formatter should return at least 2 values", ec.RegisterFormatter(func(a int) string { return "" }).Error())
+		assert.Regexp(t, "formatter should return strings before the error", ec.RegisterFormatter(func(a int) (int, error) { return 0, nil }).Error())
+		assert.Regexp(t, "last return value should be error", ec.RegisterFormatter(func(a int) (string, string) { return "", "" }).Error())
+		assert.Nil(t, ec.RegisterFormatter(FormatInt))
+		assert.Regexp(t, "already been registered for the int type", ec.RegisterFormatter(FormatInt).Error())
+	})
+}
+
+func TestMarshalErrors(t *testing.T) {
+	t.Run("not a pointer", func(t *testing.T) {
+		_, err := Marshal(marshalDB{})
+		assert.Equal(t, "envcfg: { 0} is not a pointer", err.Error())
+	})
+
+	t.Run("no formatter for templates", func(t *testing.T) {
+		type myConfig struct {
+			Tmpl *template.Template `env:"TMPL"`
+		}
+		_, err := Marshal(&myConfig{Tmpl: template.New("")})
+		assert.Equal(t, "envcfg: no formatter function found for type *template.Template (field myConfig.Tmpl)", err.Error())
+	})
+
+	t.Run("conflicting fields", func(t *testing.T) {
+		type myConfig struct {
+			A string `env:"NAME"`
+			B string `env:"NAME"`
+		}
+		_, err := Marshal(&myConfig{A: "a", B: "a"})
+		assert.Nil(t, err)
+		_, err = Marshal(&myConfig{A: "a", B: "b"})
+		assert.Equal(t, `envcfg: myConfig.A and myConfig.B both set NAME, to "a" and "b"`, err.Error())
+	})
+}
@@ -0,0 +1,331 @@
+package envcfg
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// this file holds the helpers for loading struct fields that are themselves structs, pointers to
+// structs, or collections of structs.
+
+// isNestedStruct reports whether field holds a struct (or a pointer to a struct) whose own fields
+// should be loaded:  either an embedded struct with no explicit field name, or an exported struct
+// field without an env tag.
+func isNestedStruct(field reflect.StructField, hasEnvTag bool) bool {
+	typ := field.Type
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return false
+	}
+	if field.Anonymous {
+		return field.Name == typ.Name()
+	}
+	return !hasEnvTag && field.PkgPath == ""
+}
+
+// loadStructPtr allocates a new struct for a pointer-to-struct field, loads it, and points the field
+// at it.  If the field has an optional:"true" tag and none of the struct's variables are set, then
+// the field is left nil instead.  Pointers to structs without any env tags (like an untagged
+// *url.URL) are left alone.
+func (e *Loader) loadStructPtr(
+	st *loadState,
+	prefix string,
+	field reflect.StructField,
+	fieldPath string,
+	fieldVal reflect.Value,
+) error {
+	structType := field.Type.Elem()
+	if !hasEnvTags(structType, map[reflect.Type]bool{}) {
+		return nil
+	}
+	if !fieldVal.CanSet() {
+		return fmt.Errorf("envcfg: cannot set unexported field %s", fieldPath)
+	}
+	if st.loading[structType] {
+		return fmt.Errorf("envcfg: cannot load field %s: %v refers to itself", fieldPath, structType)
+	}
+	opts, err := parseFieldOptions(field, fieldPath)
+	if err != nil {
+		return err
+	}
+
+	structVal := reflect.New(structType)
+	found := st.found
+	err = e.loadStructFields(st, prefix, fieldPath, structType, structVal.Elem())
+	if opts.optional && st.found == found {
+		// none of this section's variables were set, so it stays nil.  Complaints about the missing
+		// variables don't matter, but problems with the struct itself still do.
+		return structErrors(err)
+	}
+	fieldVal.Set(structVal)
+	return err
+}
+
+// structErrors returns err without its MissingValueErrors and EmptyValueErrors, leaving the errors
+// that come from the struct's definition, like a field with no parser.
+func structErrors(err error) error {
+	errs, ok := err.(LoadErrors)
+	if !ok {
+		errs = LoadErrors{}.append(err)
+	}
+	var out LoadErrors
+	for _, err := range errs {
+		switch err.(type) {
+		case *MissingValueError, *EmptyValueError:
+			continue
+		}
+		out = append(out, err)
+	}
+	return out.orNil()
+}
+
+// isStructSlice reports whether field is an exported slice of structs without an env tag, to be
+// filled from numbered variables.
+func isStructSlice(field reflect.StructField, hasEnvTag bool) bool {
+	return !hasEnvTag &&
+		!field.Anonymous &&
+		field.PkgPath == "" &&
+		field.Type.Kind() == reflect.Slice &&
+		field.Type.Elem().Kind() == reflect.Struct &&
+		hasEnvTags(field.Type.Elem(), map[reflect.Type]bool{})
+}
+
+// loadStructSlice fills a slice-of-structs field from numbered variables.  Element i is loaded like
+// any other struct, with prefix + "<i>_" prepended to its variable names.  Elements are read from
+// index 0 until the first index with no variables at all.  Errors from every element are returned.
+func (e *Loader) loadStructSlice(
+	st *loadState,
+	prefix string,
+	field reflect.StructField,
+	fieldPath string,
+	fieldVal reflect.Value,
+) error {
+	var errs LoadErrors
+	elemType := field.Type.Elem()
+	out := reflect.MakeSlice(field.Type, 0, 0)
+	for i := 0; ; i++ {
+		elemPrefix := prefix + strconv.Itoa(i) + "_"
+		if !st.hasPrefix(elemPrefix) {
+			break
+		}
+		elem := reflect.New(elemType).Elem()
+		elemPath := fmt.Sprintf("%s[%d]", fieldPath, i)
+		errs = errs.append(e.loadStructFields(st, elemPrefix, elemPath, elemType, elem))
+		out = reflect.Append(out, elem)
+	}
+	if out.Len() > 0 {
+		fieldVal.Set(out)
+	}
+	return errs.orNil()
+}
+
+// isStructMap reports whether field is an exported map from strings to structs without an env tag,
+// to be filled from groups of variables that share a name.
+func isStructMap(field reflect.StructField, hasEnvTag bool) bool {
+	return !hasEnvTag &&
+		!field.Anonymous &&
+		field.PkgPath == "" &&
+		field.Type.Kind() == reflect.Map &&
+		field.Type.Key().Kind() == reflect.String &&
+		field.Type.Elem().Kind() == reflect.Struct &&
+		hasEnvTags(field.Type.Elem(), map[reflect.Type]bool{})
+}
+
+// loadStructMap fills a map-of-structs field from groups of variables named
+// prefix + "<NAME>_" + <one of the struct's variable names>.  Each distinct NAME becomes a key in
+// the map, and its value is loaded like any other struct, with prefix + "<NAME>_" prepended to its
+// variable names.  Errors from every entry are returned.
+func (e *Loader) loadStructMap(
+	st *loadState,
+	prefix string,
+	field reflect.StructField,
+	fieldPath string,
+	fieldVal reflect.Value,
+) error {
+	var errs LoadErrors
+	elemType := field.Type.Elem()
+	out := reflect.MakeMap(field.Type)
+	for _, name := range groupNames(st.keysWithPrefix(prefix), prefix, structKeys(elemType)) {
+		elem := reflect.New(elemType).Elem()
+		elemPath := fmt.Sprintf("%s[%s]", fieldPath, name)
+		errs = errs.append(e.loadStructFields(st, prefix+name+"_", elemPath, elemType, elem))
+		out.SetMapIndex(reflect.ValueOf(name).Convert(field.Type.Key()), elem)
+	}
+	if out.Len() > 0 {
+		fieldVal.Set(out)
+	}
+	return errs.orNil()
+}
+
+// groupNames finds the distinct NAMEs in keys shaped like prefix + NAME + "_" + suffix, where
+// suffix is one of suffixes.  When more than one suffix fits a key, the longest one wins, so
+// TENANT_ACME_DB_HOST is in the ACME group if DB_HOST is a suffix, even if HOST is too.  The names
+// are returned in sorted order.
+func groupNames(keys []string, prefix string, suffixes []string) []string {
+	sort.Slice(suffixes, func(i, j int) bool { return len(suffixes[i]) > len(suffixes[j]) })
+	seen := map[string]bool{}
+	var names []string
+	for _, key := range keys {
+		rest := strings.TrimPrefix(key, prefix)
+		for _, suffix := range suffixes {
+			if len(rest) <= len(suffix)+1 || !strings.HasSuffix(rest, "_"+suffix) {
+				continue
+			}
+			name := rest[:len(rest)-len(suffix)-1]
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+			break
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// placeholders for the parts of variable names and field paths that vary by element in numbered
+// lists and named groups of structs, as found by walkFields.
+const (
+	indexPlaceholder = "<N>"
+	namePlaceholder  = "<NAME>"
+)
+
+// taggedField is a field with an env tag, as found by walkFields.
+type taggedField struct {
+	field reflect.StructField
+	// the field's full path from the top level struct, like Config.DB.Host.
+	path string
+	// the field's variable names, with their prefixes.
+	keys []string
+	// the values from the field's default tag, if it has one.
+	defaults   []string
+	hasDefault bool
+	// the field is tagged optional:"true", or is inside an optional pointer to a struct.
+	optional bool
+	// the field is inside a numbered list or named group of structs, so its keys and path contain
+	// placeholders.
+	dynamic bool
+	// the field is tagged secret:"true".
+	secret bool
+}
+
+// checkDefaults returns an error if the field's default tag doesn't have a value for each of its
+// variables.
+func (tf taggedField) checkDefaults() error {
+	if tf.hasDefault && len(tf.defaults) != len(tf.keys) {
+		return &TagMismatchError{
+			Field:      tf.path,
+			EnvTag:     tf.field.Tag.Get(cfgTag),
+			DefaultTag: tf.field.Tag.Get(defaultTag),
+		}
+	}
+	return nil
+}
+
+// walkFields calls fn for each field with an env tag that loadStructFields could load when loading
+// structType with the given prefix, in the order they'd be loaded.  Fields inside numbered lists and
+// maps of structs are included once, with placeholders for the index or name.  Walking stops at the
+// first error from fn, or from parsing the fields' tags.
+func walkFields(prefix, path string, structType reflect.Type, fn func(taggedField) error) error {
+	seen := map[reflect.Type]bool{}
+	var walk func(prefix, path string, typ reflect.Type, optional, dynamic bool) error
+	walk = func(prefix, path string, typ reflect.Type, optional, dynamic bool) error {
+		if seen[typ] {
+			return nil
+		}
+		seen[typ] = true
+		defer delete(seen, typ)
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			fieldPath := joinPath(path, field.Name)
+			tagVal, ok := field.Tag.Lookup(cfgTag)
+			fieldPrefix := prefix + field.Tag.Get(prefixTag)
+			opts, err := parseFieldOptions(field, fieldPath)
+			if err != nil {
+				return err
+			}
+
+			switch {
+			case isNestedStruct(field, ok):
+				nested := field.Type
+				if nested.Kind() == reflect.Ptr {
+					nested = nested.Elem()
+				}
+				err = walk(fieldPrefix, fieldPath, nested, optional || opts.optional, dynamic)
+			case isStructSlice(field, ok):
+				err = walk(fieldPrefix+indexPlaceholder+"_", fieldPath+"["+indexPlaceholder+"]",
+					field.Type.Elem(), optional, true)
+			case isStructMap(field, ok):
+				err = walk(fieldPrefix+namePlaceholder+"_", fieldPath+"["+namePlaceholder+"]",
+					field.Type.Elem(), optional, true)
+			case ok:
+				tf := taggedField{
+					field:    field,
+					path:     fieldPath,
+					optional: optional || opts.optional,
+					dynamic:  dynamic,
+					secret:   opts.secret,
+				}
+				for _, key := range strings.Split(tagVal, tagSep) {
+					tf.keys = append(tf.keys, prefix+key)
+				}
+				var defaultString string
+				defaultString, tf.hasDefault = field.Tag.Lookup(defaultTag)
+				if tf.hasDefault {
+					tf.defaults = splitDefaultTag(defaultString)
+				}
+				err = fn(tf)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return walk(prefix, path, structType, false, false)
+}
+
+// structKeys returns all the variable names that loadStructFields would look up directly when
+// loading structType with no prefix.  Names inside numbered lists and maps of structs can't be
+// known ahead of time, so they're left out.
+func structKeys(structType reflect.Type) []string {
+	var out []string
+	walkFields("", "", structType, func(tf taggedField) error {
+		if !tf.dynamic {
+			out = append(out, tf.keys...)
+		}
+		return nil
+	})
+	return out
+}
+
+// hasEnvTags reports whether any of the fields that loadStructFields would look at in structType
+// have an env tag.
+func hasEnvTags(structType reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[structType] {
+		return false
+	}
+	seen[structType] = true
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		_, ok := field.Tag.Lookup(cfgTag)
+		if ok {
+			return true
+		}
+		if isNestedStruct(field, ok) {
+			typ := field.Type
+			if typ.Kind() == reflect.Ptr {
+				typ = typ.Elem()
+			}
+			if hasEnvTags(typ, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
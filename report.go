@@ -0,0 +1,103 @@
+package envcfg
+
+// this file holds the reports of where each field's values came from in a load, for tracking down
+// config that isn't what it seems, like a default that's in use because a variable is misnamed.
+
+// An Origin says where a variable's value came from.
+type Origin int
+
+const (
+	// OriginUnset means the variable wasn't set, and its field has no default.
+	OriginUnset Origin = iota
+	// OriginSource means the value came from one of the sources other than EnvSource, like a map.
+	OriginSource
+	// OriginFlag means the value came from a flag bound with BindFlags.
+	OriginFlag
+	// OriginFile means the value came from a file named by a variable with the FileSuffix.
+	OriginFile
+	// OriginDefault means the value came from the field's default tag.
+	OriginDefault
+	// OriginEnv means the value came from the environment, through EnvSource (which is what Load
+	// uses).
+	OriginEnv
+)
+
+func (o Origin) String() string {
+	switch o {
+	case OriginSource:
+		return "source"
+	case OriginFlag:
+		return "flag"
+	case OriginFile:
+		return "file"
+	case OriginDefault:
+		return "default"
+	case OriginEnv:
+		return "env"
+	}
+	return "unset"
+}
+
+// A Report says where the values of each field with an env tag came from in a load, in the order the
+// fields were loaded.
+type Report struct {
+	Fields []FieldReport
+}
+
+// Field returns the report for the field with the given path, like Config.DB.Host, and whether
+// there is one.
+func (r *Report) Field(path string) (FieldReport, bool) {
+	for _, field := range r.Fields {
+		if field.Path == path {
+			return field, true
+		}
+	}
+	return FieldReport{}, false
+}
+
+// A FieldReport says where a field's values came from.
+type FieldReport struct {
+	// the field's full path from the struct passed to the Loader, like Config.DB.Host.
+	Path string
+	// one for each of the field's variables.
+	Vars []VarReport
+	// the parser that turned the values into the field's value, like envcfg.ParseInt or
+	// encoding.TextUnmarshaler.
+	Parser string
+	// whether the field is tagged secret:"true", in which case the values shouldn't be logged.
+	Secret bool
+}
+
+// A VarReport says where a variable's value came from.
+type VarReport struct {
+	Key string
+	// the string passed to the field's parser, or "" if the variable is unset.
+	Value  string
+	Origin Origin
+	// for OriginSource and OriginEnv, the index of the source in the list given to LoadFrom.
+	Source int
+	// the variable naming a file that was consulted because Key wasn't set, if any.
+	FileKey string
+	// for OriginDefault and OriginUnset, a variable that's set with a name similar to Key, if there
+	// is one.
+	Suggestion string
+}
+
+// LoadWithReport loads config from the environment into the provided struct, like Load, and also
+// returns a Report of where each field's values came from.  The report is returned even if
+// there's an error, unless c isn't a pointer to a struct.
+func (e *Loader) LoadWithReport(c interface{}) (*Report, error) {
+	return e.LoadFromWithReport(c, EnvSource())
+}
+
+// LoadFromWithReport loads config from the provided sources into the provided struct, like
+// LoadFrom, and also returns a Report of where each field's values came from.  The report is
+// returned even if there's an error, unless c isn't a pointer to a struct.
+func (e *Loader) LoadFromWithReport(c interface{}, sources ...Source) (*Report, error) {
+	if _, err := configType(c); err != nil {
+		return nil, err
+	}
+	report := &Report{}
+	err := e.loadFrom(c, sources, report)
+	return report, err
+}
@@ -0,0 +1,90 @@
+package envcfg
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadWithReport(t *testing.T) {
+	type myConfig struct {
+		Name     string        `env:"NAME"`
+		Timeout  time.Duration `env:"TIMEOUT" default:"5s"`
+		Password string        `env:"PASSWORD" secret:"true" file:"true"`
+		Level    logLevel      `env:"LEVEL" optional:"true"`
+		Debug    bool          `env:"DEBUG" default:"false"`
+		Port     int           `env:"PORT"`
+	}
+
+	passwordFile := filepath.Join(t.TempDir(), "password")
+	assert.Nil(t, os.WriteFile(passwordFile, []byte("hunter2"), 0600))
+
+	ec, err := New()
+	assert.Nil(t, err)
+	var conf myConfig
+	fs := flag.NewFlagSet("myapp", flag.ContinueOnError)
+	assert.Nil(t, ec.BindFlags(fs, &conf))
+	assert.Nil(t, fs.Parse([]string{"-debug"}))
+
+	report, err := ec.LoadFromWithReport(&conf,
+		MapSource{"NAME": "myapp"},
+		MapSource{"NAME": "ignored", "TIMEOUTT": "1m", "PASSWORD_FILE": passwordFile},
+	)
+	assert.Equal(t, "no PORT value found, and myConfig.Port has no default", err.Error())
+	assert.Equal(t, []FieldReport{
+		{
+			Path:   "myConfig.Name",
+			Vars:   []VarReport{{Key: "NAME", Value: "myapp", Origin: OriginSource, Source: 0}},
+			Parser: "envcfg.ParseString",
+		},
+		{
+			Path: "myConfig.Timeout",
+			Vars: []VarReport{
+				{Key: "TIMEOUT", Value: "5s", Origin: OriginDefault, Suggestion: "TIMEOUTT"},
+			},
+			Parser: "time.ParseDuration",
+		},
+		{
+			Path: "myConfig.Password",
+			Vars: []VarReport{
+				{Key: "PASSWORD", Value: "hunter2", Origin: OriginFile, FileKey: "PASSWORD_FILE"},
+			},
+			Parser: "envcfg.ParseString",
+			Secret: true,
+		},
+		{
+			Path:   "myConfig.Level",
+			Vars:   []VarReport{{Key: "LEVEL", Origin: OriginUnset}},
+			Parser: "encoding.TextUnmarshaler",
+		},
+		{
+			Path:   "myConfig.Debug",
+			Vars:   []VarReport{{Key: "DEBUG", Value: "true", Origin: OriginFlag}},
+			Parser: "strconv.ParseBool",
+		},
+		{
+			Path:   "myConfig.Port",
+			Vars:   []VarReport{{Key: "PORT", Origin: OriginUnset}},
+			Parser: "strconv.Atoi",
+		},
+	}, report.Fields)
+
+	field, ok := report.Field("myConfig.Password")
+	assert.True(t, ok)
+	assert.Equal(t, "file", field.Vars[0].Origin.String())
+	_, ok = report.Field("myConfig.Nope")
+	assert.False(t, ok)
+
+	// values from later sources get their index
+	report, err = LoadFromWithReport(&conf, MapSource{}, MapSource{"NAME": "myapp", "PASSWORD": "x", "PORT": "80"})
+	assert.Nil(t, err)
+	field, _ = report.Field("myConfig.Port")
+	assert.Equal(t, VarReport{Key: "PORT", Value: "80", Origin: OriginSource, Source: 1}, field.Vars[0])
+
+	_, err = LoadWithReport(conf)
+	assert.Contains(t, err.Error(), "is not a pointer")
+}
@@ -0,0 +1,129 @@
+package envcfg
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// this file holds the export of a config struct's variables as a JSON Schema, so that deploy tooling
+// can check a set of variables before rolling it out.
+
+// jsonSchema is the subset of JSON Schema (draft 7) used to describe a config struct.
+type jsonSchema struct {
+	Schema            string                 `json:"$schema,omitempty"`
+	Title             string                 `json:"title,omitempty"`
+	Type              string                 `json:"type,omitempty"`
+	Description       string                 `json:"description,omitempty"`
+	Default           *string                `json:"default,omitempty"`
+	Format            string                 `json:"format,omitempty"`
+	Pattern           string                 `json:"pattern,omitempty"`
+	Enum              []string               `json:"enum,omitempty"`
+	AnyOf             []*jsonSchema          `json:"anyOf,omitempty"`
+	Properties        map[string]*jsonSchema `json:"properties,omitempty"`
+	PatternProperties map[string]*jsonSchema `json:"patternProperties,omitempty"`
+	Required          []string               `json:"required,omitempty"`
+}
+
+const (
+	signedIntPattern   = `^[-+]?(0[xX][0-9a-fA-F_]+|0[bB][01_]+|0[oO][0-7_]+|[0-9][0-9_]*)$`
+	unsignedIntPattern = `^(0[xX][0-9a-fA-F_]+|0[bB][01_]+|0[oO][0-7_]+|[0-9][0-9_]*)$`
+)
+
+// schemaHints describes the strings accepted by the default parsers, by parser name.  The patterns
+// match the syntax the parsers accept (the integer parsers take any Go integer literal), and the
+// integer formats give their bit sizes, since a pattern can't easily check a range.
+var schemaHints = map[string]jsonSchema{
+	shortFuncName(funcName(ParseBool)): {
+		Enum: []string{"1", "t", "T", "TRUE", "true", "True", "0", "f", "F", "FALSE", "false", "False"},
+	},
+	shortFuncName(funcName(ParseInt)):    {Format: "int", Pattern: `^[-+]?[0-9]+$`},
+	shortFuncName(funcName(ParseInt8)):   {Format: "int8", Pattern: signedIntPattern},
+	shortFuncName(funcName(ParseInt16)):  {Format: "int16", Pattern: signedIntPattern},
+	shortFuncName(funcName(ParseInt32)):  {Format: "int32", Pattern: signedIntPattern},
+	shortFuncName(funcName(ParseInt64)):  {Format: "int64", Pattern: signedIntPattern},
+	shortFuncName(funcName(ParseUint)):   {Format: "uint64", Pattern: unsignedIntPattern},
+	shortFuncName(funcName(ParseUint8)):  {Format: "uint8", Pattern: unsignedIntPattern},
+	shortFuncName(funcName(ParseUint16)): {Format: "uint16", Pattern: unsignedIntPattern},
+	shortFuncName(funcName(ParseUint32)): {Format: "uint32", Pattern: unsignedIntPattern},
+	shortFuncName(funcName(ParseUint64)): {Format: "uint64", Pattern: unsignedIntPattern},
+	shortFuncName(funcName(ParseDuration)): {
+		Pattern: `^[-+]?(0|(([0-9]+(\.[0-9]*)?|\.[0-9]+)(ns|us|µs|μs|ms|s|m|h))+)$`,
+	},
+	shortFuncName(funcName(ParseTime)): {Format: "date-time"},
+	shortFuncName(funcName(ParseURL)):  {Format: "uri-reference"},
+	shortFuncName(funcName(ParseIP)): {
+		AnyOf: []*jsonSchema{{Format: "ipv4"}, {Format: "ipv6"}},
+	},
+}
+
+// JSONSchema returns a JSON Schema document describing the variables that c's fields are loaded
+// from, as the properties of an object.  Each variable is a string property, with the field's
+// default, the text from its desc tag, and a format or pattern for values that the default parsers
+// for types like time.Duration, *url.URL, net.IP, and the integer types accept.  Variables without
+// defaults are required unless their fields are optional.  Variables in numbered lists and named
+// groups of structs are described with patternProperties.
+//
+// c must be a pointer to a config struct, but only its type is used.
+func (e *Loader) JSONSchema(c interface{}) ([]byte, error) {
+	structType, err := configType(c)
+	if err != nil {
+		return nil, err
+	}
+	schema := &jsonSchema{
+		Schema:     "http://json-schema.org/draft-07/schema#",
+		Title:      structType.Name(),
+		Type:       "object",
+		Properties: map[string]*jsonSchema{},
+	}
+	required := map[string]bool{}
+	err = walkFields("", structType.Name(), structType, func(tf taggedField) error {
+		if err := tf.checkDefaults(); err != nil {
+			return err
+		}
+		p, ok, err := e.fieldParser(tf.field, tf.path, len(tf.keys))
+		if err != nil {
+			return err
+		}
+		for i, key := range tf.keys {
+			prop := &jsonSchema{Type: "string", Description: tf.field.Tag.Get(descTag)}
+			if hint, found := schemaHints[p.name]; ok && found {
+				prop.Format = hint.Format
+				prop.Pattern = hint.Pattern
+				prop.Enum = hint.Enum
+				prop.AnyOf = hint.AnyOf
+			}
+			if tf.hasDefault {
+				def := tf.defaults[i]
+				prop.Default = &def
+			}
+
+			if tf.dynamic {
+				if schema.PatternProperties == nil {
+					schema.PatternProperties = map[string]*jsonSchema{}
+				}
+				schema.PatternProperties[keyPattern(key)] = prop
+				continue
+			}
+			schema.Properties[key] = prop
+			if !tf.hasDefault && !tf.optional && !required[key] {
+				required[key] = true
+				schema.Required = append(schema.Required, key)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// keyPattern returns a regular expression matching the names of the variables that key stands for,
+// with its placeholders for list indexes and group names.
+func keyPattern(key string) string {
+	pattern := regexp.QuoteMeta(key)
+	pattern = strings.ReplaceAll(pattern, indexPlaceholder, "[0-9]+")
+	pattern = strings.ReplaceAll(pattern, namePlaceholder, ".+")
+	return "^" + pattern + "$"
+}
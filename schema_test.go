@@ -0,0 +1,97 @@
+package envcfg
+
+import (
+	"encoding/json"
+	"net"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONSchema(t *testing.T) {
+	type UpstreamConfig struct {
+		Host string `env:"HOST" desc:"upstream host"`
+	}
+	type myConfig struct {
+		Name      string                    `env:"NAME" desc:"the app's name"`
+		Port      int                       `env:"PORT" default:"80"`
+		Small     int8                      `env:"SMALL" optional:"true"`
+		Count     uint32                    `env:"COUNT" default:"0x10"`
+		Timeout   time.Duration             `env:"TIMEOUT" default:"5s"`
+		URL       *url.URL                  `env:"URL"`
+		IP        net.IP                    `env:"IP"`
+		Hosts     []string                  `env:"HOSTS"`
+		Upstreams []UpstreamConfig          `envPrefix:"UPSTREAM_"`
+		Tenants   map[string]UpstreamConfig `envPrefix:"TENANT_"`
+	}
+
+	ec, err := New()
+	assert.Nil(t, err)
+	schema, err := ec.JSONSchema(&myConfig{})
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{
+		"$schema": "http://json-schema.org/draft-07/schema#",
+		"title": "myConfig",
+		"type": "object",
+		"properties": {
+			"NAME": {"type": "string", "description": "the app's name"},
+			"PORT": {"type": "string", "default": "80", "format": "int", "pattern": "^[-+]?[0-9]+$"},
+			"SMALL": {
+				"type": "string",
+				"format": "int8",
+				"pattern": "^[-+]?(0[xX][0-9a-fA-F_]+|0[bB][01_]+|0[oO][0-7_]+|[0-9][0-9_]*)$"
+			},
+			"COUNT": {
+				"type": "string",
+				"default": "0x10",
+				"format": "uint32",
+				"pattern": "^(0[xX][0-9a-fA-F_]+|0[bB][01_]+|0[oO][0-7_]+|[0-9][0-9_]*)$"
+			},
+			"TIMEOUT": {
+				"type": "string",
+				"default": "5s",
+				"pattern": "^[-+]?(0|(([0-9]+(\\.[0-9]*)?|\\.[0-9]+)(ns|us|µs|μs|ms|s|m|h))+)$"
+			},
+			"URL": {"type": "string", "format": "uri-reference"},
+			"IP": {"type": "string", "anyOf": [{"format": "ipv4"}, {"format": "ipv6"}]},
+			"HOSTS": {"type": "string"}
+		},
+		"patternProperties": {
+			"^UPSTREAM_[0-9]+_HOST$": {"type": "string", "description": "upstream host"},
+			"^TENANT_.+_HOST$": {"type": "string", "description": "upstream host"}
+		},
+		"required": ["NAME", "URL", "IP", "HOSTS"]
+	}`, string(schema))
+
+	// the patterns agree with the parsers
+	var doc jsonSchema
+	assert.Nil(t, json.Unmarshal(schema, &doc))
+	cases := []struct {
+		key, val string
+	}{
+		{"PORT", "-8080"},
+		{"SMALL", "0x7f"},
+		{"SMALL", "-1_0"},
+		{"COUNT", "0b1010"},
+		{"TIMEOUT", "1h2m3.5s"},
+		{"TIMEOUT", "-.5µs"},
+		{"TIMEOUT", "0"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.key+"="+tc.val, func(t *testing.T) {
+			assert.Regexp(t, regexp.MustCompile(doc.Properties[tc.key].Pattern), tc.val)
+			err := LoadFromMap(map[string]string{
+				"NAME": "x", "URL": "x", "IP": "::1", "HOSTS": "", tc.key: tc.val,
+			}, &myConfig{})
+			assert.Nil(t, err)
+		})
+	}
+	assert.NotRegexp(t, regexp.MustCompile(doc.Properties["COUNT"].Pattern), "-1")
+	assert.NotRegexp(t, regexp.MustCompile(doc.Properties["TIMEOUT"].Pattern), "5")
+
+	_, err = JSONSchema(myConfig{})
+	assert.Contains(t, err.Error(), "is not a pointer")
+}
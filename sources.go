@@ -0,0 +1,93 @@
+package envcfg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// A Source is somewhere to read config variables from, like the environment, a map, or a .env
+// file.  Loader.LoadFrom takes several of them, to layer config from different places.
+type Source interface {
+	// Lookup returns the value of the named variable, and whether the source has it.
+	Lookup(key string) (string, bool)
+	// Keys returns the names of all the variables the source has.
+	Keys() []string
+}
+
+// MapSource is a Source that reads variables from a map.
+type MapSource map[string]string
+
+// Lookup returns the value of the named variable, and whether the map has it.
+func (m MapSource) Lookup(key string) (string, bool) {
+	val, ok := m[key]
+	return val, ok
+}
+
+// Keys returns the names of all the variables in the map, sorted.
+func (m MapSource) Keys() []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// EnvSource returns a Source holding the process's environment variables as they are when it's
+// called.  Reports say which values came from it, as OriginEnv.
+func EnvSource() Source {
+	return envSource{MapSource(envListToMap(os.Environ()))}
+}
+
+// envSource is the Source returned by EnvSource, a type of its own so that reports can tell it
+// apart from other sources.
+type envSource struct {
+	MapSource
+}
+
+// SecretDirSource returns a Source holding a variable for each file in dir, named after the file
+// and set to its contents with surrounding whitespace trimmed.  That's how Kubernetes and Docker
+// mount secrets as volumes (at paths like /etc/secrets or /run/secrets).  Hidden files and
+// subdirectories are skipped, which takes care of the ..data symlink and timestamped directory that
+// Kubernetes uses to swap in new secret values.  The files are read when SecretDirSource is called.
+func SecretDirSource(dir string) (Source, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("envcfg: %v", err)
+	}
+	vals := map[string]string{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		// stat rather than using entry.IsDir, to follow symlinks.
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("envcfg: %v", err)
+		}
+		if info.IsDir() {
+			continue
+		}
+		val, err := readValueFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("envcfg: %v", err)
+		}
+		vals[name] = val
+	}
+	return MapSource(vals), nil
+}
+
+// readValueFile returns the contents of the named file with surrounding whitespace (like the
+// trailing newline most editors add) trimmed.
+func readValueFile(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
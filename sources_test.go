@@ -0,0 +1,120 @@
+package envcfg
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecretDirSource(t *testing.T) {
+	// lay out a directory the way Kubernetes mounts a secret volume
+	dir := t.TempDir()
+	dataDir := filepath.Join(dir, "..2024_01_02_03_04_05.123456789")
+	assert.Nil(t, os.Mkdir(dataDir, 0700))
+	assert.Nil(t, os.WriteFile(filepath.Join(dataDir, "DB_PASSWORD"), []byte("hunter2\n"), 0600))
+	assert.Nil(t, os.WriteFile(filepath.Join(dataDir, "API_KEY"), []byte("  abc123  "), 0600))
+	assert.Nil(t, os.Symlink(filepath.Base(dataDir), filepath.Join(dir, "..data")))
+	assert.Nil(t, os.Symlink(filepath.Join("..data", "DB_PASSWORD"), filepath.Join(dir, "DB_PASSWORD")))
+	assert.Nil(t, os.Symlink(filepath.Join("..data", "API_KEY"), filepath.Join(dir, "API_KEY")))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, ".hidden"), []byte("nope"), 0600))
+	assert.Nil(t, os.Mkdir(filepath.Join(dir, "subdir"), 0700))
+
+	source, err := SecretDirSource(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"API_KEY", "DB_PASSWORD"}, source.Keys())
+
+	type myConfig struct {
+		Password string `env:"DB_PASSWORD"`
+		APIKey   string `env:"API_KEY"`
+		Host     string `env:"DB_HOST"`
+	}
+	var conf myConfig
+	err = LoadFrom(&conf, MapSource{"DB_HOST": "localhost"}, source)
+	assert.Nil(t, err)
+	assert.Equal(t, myConfig{Password: "hunter2", APIKey: "abc123", Host: "localhost"}, conf)
+}
+
+func TestSecretDirSourceMissing(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "missing")
+	_, err := SecretDirSource(dir)
+	assert.Equal(t, "envcfg: open "+dir+": no such file or directory", err.Error())
+}
+
+func TestFileSuffix(t *testing.T) {
+	dir := t.TempDir()
+	passwordFile := filepath.Join(dir, "db_password")
+	assert.Nil(t, os.WriteFile(passwordFile, []byte("hunter2\n"), 0600))
+	missingFile := filepath.Join(dir, "missing")
+
+	type myConfig struct {
+		Password string `env:"DB_PASSWORD"`
+		User     string `env:"DB_USER" file:"false"`
+	}
+	type taggedConfig struct {
+		Password string `env:"DB_PASSWORD" file:"true"`
+		User     string `env:"DB_USER" default:"postgres"`
+	}
+
+	// with the option, any field can be read from a file
+	ec, err := New(FileSuffix("_FILE"), StrictPrefix("DB_"))
+	assert.Nil(t, err)
+	var conf myConfig
+	err = ec.LoadFromMap(map[string]string{"DB_PASSWORD_FILE": passwordFile, "DB_USER": "me"}, &conf)
+	assert.Nil(t, err)
+	assert.Equal(t, myConfig{Password: "hunter2", User: "me"}, conf)
+
+	// the variable itself wins
+	err = ec.LoadFromMap(map[string]string{
+		"DB_PASSWORD":      "swordfish",
+		"DB_PASSWORD_FILE": passwordFile,
+		"DB_USER":          "me",
+	}, &conf)
+	assert.Equal(t, "DB_PASSWORD_FILE is set, but no field uses it", err.Error())
+	assert.Equal(t, "swordfish", conf.Password)
+
+	// fields tagged file:"false" don't read files
+	err = ec.LoadFromMap(map[string]string{"DB_PASSWORD": "swordfish", "DB_USER_FILE": passwordFile}, &conf)
+	assert.Equal(t,
+		"no DB_USER value found, and myConfig.User has no default\n"+
+			"DB_USER_FILE is set, but no field uses it",
+		err.Error(),
+	)
+
+	// without the option, only tagged fields read files
+	var tagged taggedConfig
+	err = LoadFromMap(map[string]string{"DB_PASSWORD_FILE": passwordFile, "DB_USER_FILE": passwordFile}, &tagged)
+	assert.Nil(t, err)
+	assert.Equal(t, taggedConfig{Password: "hunter2", User: "postgres"}, tagged)
+
+	// an unreadable file is reported with its path
+	err = LoadFromMap(map[string]string{"DB_PASSWORD_FILE": missingFile}, &tagged)
+	assert.Equal(t,
+		"envcfg: cannot read file "+missingFile+" (from DB_PASSWORD_FILE) for taggedConfig.Password: "+
+			"no such file or directory",
+		err.Error(),
+	)
+	var fileErr *FileReadError
+	assert.True(t, errors.As(err, &fileErr))
+	assert.Equal(t, missingFile, fileErr.Path)
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+
+	// even for an optional field
+	type optionalConfig struct {
+		Password string `env:"DB_PASSWORD" optional:"true" file:"true"`
+	}
+	var optional optionalConfig
+	err = LoadFromMap(map[string]string{"DB_PASSWORD_FILE": missingFile}, &optional)
+	assert.True(t, errors.As(err, &fileErr))
+	assert.Nil(t, LoadFromMap(map[string]string{}, &optional))
+	assert.Equal(t, "", optional.Password)
+
+	// so is a bad tag
+	type badConfig struct {
+		Password string `env:"DB_PASSWORD" file:"yes"`
+	}
+	err = LoadFromMap(map[string]string{}, &badConfig{})
+	assert.Equal(t, `envcfg: file tag on field badConfig.Password should be true or false, not "yes"`, err.Error())
+}
@@ -0,0 +1,156 @@
+package envcfg
+
+import (
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// loadState holds the state of a single call to LoadFrom, shared by all the recursive calls to
+// loadStructFields.
+type loadState struct {
+	// where to look up variables, highest priority first.
+	sources []Source
+
+	// the names of all the variables in all the sources, sorted.
+	keys []string
+
+	// the number of variables that have been found so far.  Comparing this before and after
+	// loading a struct tells whether any of its variables were set.
+	found int
+
+	// the names of the variables that have been found so far.
+	used map[string]bool
+
+	// the variable names that the config struct will look up, not counting the ones in numbered
+	// lists or maps of structs.  These are never suggested as corrections for missing names.
+	wanted map[string]bool
+
+	// the struct types currently being loaded, so we can refuse to recurse forever on
+	// self-referential types.
+	loading map[reflect.Type]bool
+
+	// the number of sources at the start of sources that hold flags bound with BindFlags.
+	numFlags int
+
+	// if non-nil, where each field's values came from is added to report.
+	report *Report
+}
+
+func newLoadState(sources []Source, structType reflect.Type) *loadState {
+	seen := map[string]bool{}
+	var keys []string
+	for _, source := range sources {
+		for _, key := range source.Keys() {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+
+	return &loadState{
+		sources: sources,
+		keys:    keys,
+		used:    map[string]bool{},
+		loading: map[reflect.Type]bool{},
+		wanted:  prefixedKeys("", structType),
+	}
+}
+
+// lookup returns the value of the named variable from the first source that has it, the index of
+// that source, and whether any of them had it.
+func (s *loadState) lookup(key string) (string, int, bool) {
+	for i, source := range s.sources {
+		val, ok := source.Lookup(key)
+		if ok {
+			s.found++
+			s.used[key] = true
+			return val, i, true
+		}
+	}
+	return "", 0, false
+}
+
+// record adds a field to the report, if there is one.
+func (s *loadState) record(field FieldReport) {
+	if s.report != nil {
+		s.report.Fields = append(s.report.Fields, field)
+	}
+}
+
+// origin returns the Origin of a value from the source at index i in sources, and the index of the
+// source in the list given to LoadFrom.
+func (s *loadState) origin(i int) (Origin, int) {
+	if i < s.numFlags {
+		return OriginFlag, 0
+	}
+	if _, ok := s.sources[i].(envSource); ok {
+		return OriginEnv, i - s.numFlags
+	}
+	return OriginSource, i - s.numFlags
+}
+
+// lookupFile looks up the variable key, and if it's set, returns the contents of the file it names.
+// A file that can't be read is reported as a FileReadError for the field at fieldPath.
+func (s *loadState) lookupFile(fieldPath, key string) (string, bool, error) {
+	path, _, ok := s.lookup(key)
+	if !ok {
+		return "", false, nil
+	}
+	val, err := readValueFile(path)
+	if err != nil {
+		if pathErr, ok := err.(*os.PathError); ok {
+			// the path is already in the FileReadError.
+			err = pathErr.Err
+		}
+		return "", false, &FileReadError{Field: fieldPath, EnvKey: key, Path: path, Err: err}
+	}
+	return val, true, nil
+}
+
+// hasPrefix reports whether any variable name starts with prefix.
+func (s *loadState) hasPrefix(prefix string) bool {
+	for _, key := range s.keys {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// keysWithPrefix returns the names of all the variables that start with prefix.
+func (s *loadState) keysWithPrefix(prefix string) []string {
+	var out []string
+	for _, key := range s.keys {
+		if strings.HasPrefix(key, prefix) {
+			out = append(out, key)
+		}
+	}
+	return out
+}
+
+// unused returns an UnusedValueError for each variable with one of the given prefixes that hasn't
+// been looked up, sorted by name.
+func (s *loadState) unused(prefixes []string) []error {
+	var keys []string
+	for _, key := range s.keys {
+		if s.used[key] {
+			continue
+		}
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(key, prefix) {
+				keys = append(keys, key)
+				break
+			}
+		}
+	}
+
+	var errs []error
+	for _, key := range keys {
+		errs = append(errs, &UnusedValueError{EnvKey: key})
+	}
+	return errs
+}
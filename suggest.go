@@ -0,0 +1,104 @@
+package envcfg
+
+import (
+	"reflect"
+	"strings"
+)
+
+// this file holds the helpers that look for a variable that was probably meant when a required one
+// is missing, so that a misspelled name in a deploy manifest is easy to spot.
+
+// suggest returns the name of the variable that's the closest match to key, or "" if none is close
+// enough.  Variables that some field is known to want (because they're listed in wanted, or in the
+// env tags of the struct being loaded) are never suggested, since they aren't typos.  A name that
+// differs only in case is the best match.  Otherwise the name with the fewest single-character
+// edits wins, as long as that's no more than a fifth of key's length (but at least 1 and at most
+// 2).  Names that differ from key only in their digits, like UPSTREAM_1_HOST and UPSTREAM_0_HOST,
+// are numbered siblings rather than typos, so they're never suggested.
+func (s *loadState) suggest(key string, wanted map[string]bool) string {
+	maxDist := len(key) / 5
+	if maxDist < 1 {
+		maxDist = 1
+	}
+	if maxDist > 2 {
+		maxDist = 2
+	}
+
+	// the keys are sorted, so ties always go the same way.
+	best := ""
+	bestDist := maxDist + 1
+	upperKey := strings.ToUpper(key)
+	for _, candidate := range s.keys {
+		if candidate == key || wanted[candidate] || s.wanted[candidate] || digitsDiffer(candidate, key) {
+			continue
+		}
+		upperCandidate := strings.ToUpper(candidate)
+		if upperCandidate == upperKey {
+			return candidate
+		}
+		if d := editDistance(upperKey, upperCandidate); d < bestDist {
+			best, bestDist = candidate, d
+		}
+	}
+	return best
+}
+
+// prefixedKeys returns the set of variable names that loadStructFields will look up directly when
+// loading structType with the given prefix.
+func prefixedKeys(prefix string, structType reflect.Type) map[string]bool {
+	out := map[string]bool{}
+	for _, key := range structKeys(structType) {
+		out[prefix+key] = true
+	}
+	return out
+}
+
+// digitsDiffer reports whether a and b are the same length and differ only in positions where both
+// have a digit.
+func digitsDiffer(a, b string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := 0; i < len(a); i++ {
+		if a[i] != b[i] && !(isDigit(a[i]) && isDigit(b[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// editDistance returns the Levenshtein distance between a and b: the number of single-byte
+// insertions, deletions, and substitutions needed to turn one into the other.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
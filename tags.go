@@ -0,0 +1,87 @@
+package envcfg
+
+import (
+	"reflect"
+	"strconv"
+)
+
+// this file holds the parsing of the struct tags that tweak how a field is loaded.  Each option
+// gets its own tag, rather than being squeezed into the env tag, where commas already separate
+// variable names.
+
+const (
+	optionalTag = "optional"
+	notEmptyTag = "notempty"
+	fileTag     = "file"
+	descTag     = "desc"
+	secretTag   = "secret"
+
+	// defaultFileSuffix is used by fields tagged file:"true" when the Loader has no FileSuffix.
+	defaultFileSuffix = "_FILE"
+)
+
+// fieldOptions are the per-field settings read from a field's tags.
+type fieldOptions struct {
+	// optional fields are left alone if none of their variables are set.  Optional pointers to
+	// structs are left nil if none of the struct's variables are set.
+	optional bool
+	// notEmpty fields reject empty values, whether they come from the variables or the default tag.
+	notEmpty bool
+	// secret fields hold values like passwords, which belong in a Kubernetes Secret rather than a
+	// ConfigMap.
+	secret bool
+}
+
+func parseFieldOptions(field reflect.StructField, fieldPath string) (fieldOptions, error) {
+	var opts fieldOptions
+	var err error
+	if opts.optional, err = boolTag(field, fieldPath, optionalTag); err != nil {
+		return opts, err
+	}
+	if opts.notEmpty, err = boolTag(field, fieldPath, notEmptyTag); err != nil {
+		return opts, err
+	}
+	if opts.secret, err = boolTag(field, fieldPath, secretTag); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
+
+// FileSuffix makes the Loader read each field's value from a file when its variable isn't set but
+// the same name plus suffix is, following the convention of Docker images that accept
+// POSTGRES_PASSWORD_FILE in place of POSTGRES_PASSWORD.  The variable itself still wins when both
+// are set.  Fields tagged file:"false" are left out.
+func FileSuffix(suffix string) Option {
+	return func(e *Loader) {
+		e.fileSuffix = suffix
+	}
+}
+
+// fieldFileSuffix returns the suffix of the variables that name files holding field's values, or ""
+// if field doesn't read files.  A file tag on the field overrides the Loader's FileSuffix option.
+func (e *Loader) fieldFileSuffix(field reflect.StructField, fieldPath string) (string, error) {
+	if _, ok := field.Tag.Lookup(fileTag); !ok {
+		return e.fileSuffix, nil
+	}
+	file, err := boolTag(field, fieldPath, fileTag)
+	if err != nil || !file {
+		return "", err
+	}
+	if e.fileSuffix != "" {
+		return e.fileSuffix, nil
+	}
+	return defaultFileSuffix, nil
+}
+
+// boolTag reads a true/false value from the named tag on field.  A missing tag means false.
+func boolTag(field reflect.StructField, fieldPath string, tag string) (bool, error) {
+	s, ok := field.Tag.Lookup(tag)
+	if !ok {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, &InvalidTagError{Field: fieldPath, Tag: tag, Value: s, Want: "true or false"}
+	}
+	return b, nil
+}
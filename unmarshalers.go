@@ -0,0 +1,134 @@
+package envcfg
+
+import (
+	"encoding"
+	"encoding/json"
+	"flag"
+	"reflect"
+)
+
+// this file holds the fallbacks that let a Loader parse types that know how to parse themselves,
+// so that users don't have to register a parser func for every type with an UnmarshalText method.
+
+// A Strategy is one of the ways a Loader can turn strings into a value for a struct field.
+type Strategy int
+
+const (
+	// ParserFuncs uses the funcs registered with RegisterParser.
+	ParserFuncs Strategy = iota
+	// TextUnmarshalers uses the type's encoding.TextUnmarshaler implementation.
+	TextUnmarshalers
+	// FlagValues uses the type's flag.Value implementation (its Set(string) error method).
+	FlagValues
+	// JSONUnmarshalers uses the type's json.Unmarshaler implementation.  The variable's value is
+	// passed to UnmarshalJSON as is, so it must be a JSON document.
+	JSONUnmarshalers
+	// BinaryUnmarshalers uses the type's encoding.BinaryUnmarshaler implementation, passing it the
+	// raw bytes of the variable's value.
+	BinaryUnmarshalers
+)
+
+// DefaultStrategies is the order in which a Loader tries to find a parser for a field's type unless
+// it's configured otherwise with the ResolutionOrder option.
+var DefaultStrategies = []Strategy{
+	ParserFuncs,
+	TextUnmarshalers,
+	FlagValues,
+	JSONUnmarshalers,
+	BinaryUnmarshalers,
+}
+
+// ResolutionOrder sets the order in which a Loader tries each Strategy when looking for a way to
+// parse a field.  Strategies that are left out aren't used at all, so for example
+// ResolutionOrder(ParserFuncs) turns off all of the interface fallbacks.
+func ResolutionOrder(strategies ...Strategy) Option {
+	return func(e *Loader) {
+		e.strategies = append([]Strategy{}, strategies...)
+	}
+}
+
+var (
+	textUnmarshalerType   = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	binaryUnmarshalerType = reflect.TypeOf((*encoding.BinaryUnmarshaler)(nil)).Elem()
+	flagValueType         = reflect.TypeOf((*flag.Value)(nil)).Elem()
+	jsonUnmarshalerType   = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+// findParser returns the parser for fields of type typ that are loaded from numArgs variables,
+// trying each of the Loader's strategies in order.  The interface fallbacks only apply to fields
+// loaded from a single variable.
+func (e *Loader) findParser(typ reflect.Type, numArgs int) (parser, bool) {
+	for _, strategy := range e.strategies {
+		if strategy != ParserFuncs && numArgs != 1 {
+			continue
+		}
+		var p parser
+		var ok bool
+		switch strategy {
+		case ParserFuncs:
+			p, ok = e.parsers[parserKey{typ: typ, numArgs: numArgs}]
+		case TextUnmarshalers:
+			p, ok = interfaceParser(typ, textUnmarshalerType, "encoding.TextUnmarshaler", func(target interface{}, s string) error {
+				return target.(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+			})
+		case FlagValues:
+			p, ok = interfaceParser(typ, flagValueType, "flag.Value", func(target interface{}, s string) error {
+				return target.(flag.Value).Set(s)
+			})
+		case JSONUnmarshalers:
+			p, ok = interfaceParser(typ, jsonUnmarshalerType, "json.Unmarshaler", func(target interface{}, s string) error {
+				return target.(json.Unmarshaler).UnmarshalJSON([]byte(s))
+			})
+		case BinaryUnmarshalers:
+			p, ok = interfaceParser(typ, binaryUnmarshalerType, "encoding.BinaryUnmarshaler", func(target interface{}, s string) error {
+				return target.(encoding.BinaryUnmarshaler).UnmarshalBinary([]byte(s))
+			})
+		}
+		if ok {
+			return p, true
+		}
+	}
+	return parser{}, false
+}
+
+// interfaceParser builds a parser for typ out of an interface that typ (or *typ) implements, called
+// ifaceName in usage text.  The unmarshal func is called with a freshly allocated pointer that
+// implements iface, and the input string.
+func interfaceParser(
+	typ reflect.Type,
+	iface reflect.Type,
+	ifaceName string,
+	unmarshal func(target interface{}, s string) error,
+) (parser, bool) {
+	switch {
+	case reflect.PtrTo(typ).Implements(iface):
+		// the common case: methods with pointer receivers on a non-pointer field type like
+		// netip.Addr.  Unmarshal into a new *typ and set the field to what it points at.
+		return parser{
+			f: func(ss ...string) (reflect.Value, error) {
+				v := reflect.New(typ)
+				if err := unmarshal(v.Interface(), ss[0]); err != nil {
+					return reflect.Value{}, err
+				}
+				return v.Elem(), nil
+			},
+			numArgs: 1,
+			name:    ifaceName,
+		}, true
+	case typ.Kind() == reflect.Ptr && typ.Implements(iface):
+		// pointer field types like *big.Int.  Allocate the thing pointed to and set the field to
+		// the pointer.
+		return parser{
+			f: func(ss ...string) (reflect.Value, error) {
+				v := reflect.New(typ.Elem())
+				if err := unmarshal(v.Interface(), ss[0]); err != nil {
+					return reflect.Value{}, err
+				}
+				return v, nil
+			},
+			numArgs: 1,
+			name:    ifaceName,
+		}, true
+	}
+	return parser{}, false
+}
@@ -0,0 +1,86 @@
+package envcfg
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// this file holds the generation of help text from a config struct, so the list of variables a
+// service reads can't drift from the code.
+
+// WriteUsage writes a table to w describing each variable that c's fields are loaded from: its Go
+// type, its default, whether it's required, what parses it, and the text from the field's desc tag.
+// c must be a pointer to a config struct, but only its type is used.  Variables in numbered lists
+// and named groups of structs are listed once, with <N> or <NAME> in place of the index or name.
+func (e *Loader) WriteUsage(w io.Writer, c interface{}) error {
+	structType, err := configType(c)
+	if err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	tw := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "VARIABLE\tTYPE\tDEFAULT\tREQUIRED\tPARSER\tDESCRIPTION")
+	err = walkFields("", structType.Name(), structType, func(tf taggedField) error {
+		if err := tf.checkDefaults(); err != nil {
+			return err
+		}
+		parserName := "none"
+		p, ok, err := e.fieldParser(tf.field, tf.path, len(tf.keys))
+		if err != nil {
+			return err
+		}
+		if ok {
+			parserName = p.name
+		}
+		required := "yes"
+		if tf.optional || tf.hasDefault {
+			required = "no"
+		}
+		for i, key := range tf.keys {
+			def := ""
+			if tf.hasDefault {
+				def = usageDefault(tf.defaults[i])
+			}
+			fmt.Fprintf(tw, "%s\t%v\t%s\t%s\t%s\t%s\n",
+				key, tf.field.Type, def, required, parserName, tf.field.Tag.Get(descTag))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	tw.Flush()
+
+	// tabwriter pads empty descriptions with spaces.  Trim them.
+	for _, line := range strings.SplitAfter(b.String(), "\n") {
+		if line == "" {
+			continue
+		}
+		if _, err := io.WriteString(w, strings.TrimRight(line, " \n")+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Usage returns the table that WriteUsage would write for c.
+func (e *Loader) Usage(c interface{}) (string, error) {
+	var b bytes.Buffer
+	if err := e.WriteUsage(&b, c); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// usageDefault formats a default value for a usage table, quoting it if it'd be hard to read as is.
+func usageDefault(s string) string {
+	q := strconv.Quote(s)
+	if s == "" || q[1:len(q)-1] != s || strings.ContainsAny(s, " \t") {
+		return q
+	}
+	return s
+}
@@ -0,0 +1,67 @@
+package envcfg
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUsage(t *testing.T) {
+	type UpstreamConfig struct {
+		Host string `env:"HOST" desc:"upstream host"`
+		Port int    `env:"PORT" default:"80"`
+	}
+	type SentryConfig struct {
+		DSN string `env:"SENTRY_DSN"`
+	}
+	type pair struct{ A, B string }
+	type myConfig struct {
+		Name      string                    `env:"NAME" desc:"the app's name"`
+		Greeting  string                    `env:"GREETING" default:"hello world"`
+		Timeout   time.Duration             `env:"TIMEOUT" default:"5s"`
+		Hosts     []string                  `env:"HOSTS" optional:"true"`
+		Level     logLevel                  `env:"LEVEL" default:"info"`
+		Pair      pair                      `env:"PAIR_A,PAIR_B"`
+		Sentry    *SentryConfig             `optional:"true"`
+		Upstreams []UpstreamConfig          `envPrefix:"UPSTREAM_"`
+		Tenants   map[string]UpstreamConfig `envPrefix:"TENANT_"`
+	}
+
+	ec, err := New()
+	assert.Nil(t, err)
+	usage, err := ec.Usage(&myConfig{})
+	assert.Nil(t, err)
+	assert.Equal(t, strings.Join([]string{
+		"VARIABLE            TYPE             DEFAULT        REQUIRED  PARSER                                             DESCRIPTION",
+		"NAME                string                          yes       envcfg.ParseString                                 the app's name",
+		`GREETING            string           "hello world"  no        envcfg.ParseString`,
+		"TIMEOUT             time.Duration    5s             no        time.ParseDuration",
+		"HOSTS               []string                        no        envcfg.ParseString for each ','-separated element",
+		"LEVEL               envcfg.logLevel  info           no        encoding.TextUnmarshaler",
+		"PAIR_A              envcfg.pair                     yes       none",
+		"PAIR_B              envcfg.pair                     yes       none",
+		"SENTRY_DSN          string                          no        envcfg.ParseString",
+		"UPSTREAM_<N>_HOST   string                          yes       envcfg.ParseString                                 upstream host",
+		"UPSTREAM_<N>_PORT   int              80             no        strconv.Atoi",
+		"TENANT_<NAME>_HOST  string                          yes       envcfg.ParseString                                 upstream host",
+		"TENANT_<NAME>_PORT  int              80             no        strconv.Atoi",
+		"",
+	}, "\n"), usage)
+
+	// the writer version, and bad structs
+	var b bytes.Buffer
+	assert.Nil(t, WriteUsage(&b, &myConfig{}))
+	assert.Equal(t, usage, b.String())
+
+	_, err = Usage("myConfig")
+	assert.Equal(t, "envcfg: myConfig is not a pointer", err.Error())
+
+	type badConfig struct {
+		Hosts []string `env:"HOSTS" sep:";;"`
+	}
+	_, err = Usage(&badConfig{})
+	assert.Equal(t, `envcfg: sep tag on field badConfig.Hosts should be a single character, not ";;"`, err.Error())
+}